@@ -0,0 +1,306 @@
+package main
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/c-bata/go-prompt"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+var (
+	errNoBytecode        = errors.New("no bytecode found")
+	errUnlinkedBytecode  = errors.New("the bytecode must be linked to libraries")
+	errNoContracts       = errors.New("no contracts found")
+	errUnknownContract   = errors.New("unknown contract")
+	errUnknownABIFormat  = errors.New("unknown abi format")
+	errMultipleContracts = errors.New("multiple contracts found, choose one with --contract")
+)
+
+// contractArtifact is a contract abi with the bytecode and the deployed addresses found along with it
+type contractArtifact struct {
+	name     string
+	abi      *abi.ABI
+	bytecode []byte
+	// libraries the bytecode must be linked to before it can be deployed
+	unlinked []string
+	// deployed addresses by chain id
+	networks map[string]common.Address
+}
+
+// deployBytecode returns the bytecode, or why it can't be deployed
+func (a *contractArtifact) deployBytecode() ([]byte, error) {
+	if len(a.unlinked) != 0 {
+		return nil, unlinkedError(a.unlinked)
+	}
+	if a.bytecode == nil {
+		return nil, errNoBytecode
+	}
+	return a.bytecode, nil
+}
+
+func unlinkedError(libs []string) error {
+	return fmt.Errorf("%w: %s", errUnlinkedBytecode, strings.Join(libs, ", "))
+}
+
+// networkAddress returns the address the contract was deployed at on the chain
+func (a *contractArtifact) networkAddress(chainID *big.Int) (common.Address, bool) {
+	addr, ok := a.networks[chainID.String()]
+	return addr, ok
+}
+
+// readArtifacts reads the contracts in a file. the file can be a bare abi, a solc
+// combined-json bundle or a truffle, hardhat or foundry artifact
+func readArtifacts(fn string) ([]*contractArtifact, error) {
+	b, err := ioutil.ReadFile(fn)
+	if err != nil {
+		return nil, err
+	}
+	name := strings.TrimSuffix(filepath.Base(fn), filepath.Ext(fn))
+	b = bytes.TrimSpace(b)
+	if bytes.HasPrefix(b, []byte("[")) {
+		contractABI, err := parseABI(b)
+		if err != nil {
+			return nil, err
+		}
+		return []*contractArtifact{{name: name, abi: contractABI}}, nil
+	}
+	var fields map[string]json.RawMessage
+	if err = json.Unmarshal(b, &fields); err != nil {
+		return nil, err
+	}
+	if _, ok := fields["contracts"]; ok {
+		return parseCombinedJSON(b)
+	}
+	if _, ok := fields["abi"]; ok {
+		a, err := parseArtifact(name, b)
+		if err != nil {
+			return nil, err
+		}
+		return []*contractArtifact{a}, nil
+	}
+	return nil, errUnknownABIFormat
+}
+
+// parseABI parses an abi given as a json array or, like older solc versions output, as a json string
+func parseABI(m json.RawMessage) (*abi.ABI, error) {
+	if bytes.HasPrefix(bytes.TrimSpace(m), []byte("\"")) {
+		var s string
+		if err := json.Unmarshal(m, &s); err != nil {
+			return nil, err
+		}
+		m = json.RawMessage(s)
+	}
+	r, err := abi.JSON(bytes.NewReader(m))
+	if err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+// linkReferences are the library references of hardhat and foundry artifacts, by source and name
+type linkReferences map[string]map[string]json.RawMessage
+
+// libraryPlaceholderLen is the length of the library placeholders in unlinked bytecode
+const libraryPlaceholderLen = 40
+
+// parseBytecode decodes the bytecode of an artifact. unlinked bytecode can't be decoded, the
+// libraries it must be linked to are returned instead
+func parseBytecode(s string, refs linkReferences, known []string) ([]byte, []string) {
+	s = strings.TrimSpace(s)
+	if strings.Contains(s, "__") {
+		return nil, unlinkedLibraries(s, refs, known)
+	}
+	r, err := parseHex(s)
+	if err != nil || len(r) == 0 {
+		return nil, nil
+	}
+	return r, nil
+}
+
+// unlinkedLibraries names the libraries of unlinked bytecode from the link references or from
+// the placeholders. the hashed placeholders of solc 0.5 and later are matched with the known
+// contracts, path:name
+func unlinkedLibraries(s string, refs linkReferences, known []string) []string {
+	var r []string
+	for source, libs := range refs {
+		for name := range libs {
+			r = append(r, source+":"+name)
+		}
+	}
+	if len(r) != 0 {
+		sort.Strings(r)
+		return r
+	}
+	hashes := make(map[string]string, len(known))
+	for _, i := range known {
+		hashes["$"+hex.EncodeToString(crypto.Keccak256([]byte(i))[:17])+"$"] = i
+	}
+	seen := make(map[string]bool, 4)
+	for {
+		n := strings.Index(s, "__")
+		if n < 0 {
+			return r
+		}
+		end := n + libraryPlaceholderLen
+		if end > len(s) {
+			end = len(s)
+		}
+		name := strings.Trim(s[n:end], "_")
+		if lib, ok := hashes[name]; ok {
+			name = lib
+		}
+		if !seen[name] {
+			seen[name] = true
+			r = append(r, name)
+		}
+		s = s[end:]
+	}
+}
+
+// parseArtifact parses a truffle, hardhat or foundry artifact
+func parseArtifact(name string, b []byte) (*contractArtifact, error) {
+	var artifact struct {
+		ContractName string          `json:"contractName"`
+		ABI          json.RawMessage `json:"abi"`
+		Bytecode     json.RawMessage `json:"bytecode"`
+		// hardhat keeps the link references next to the bytecode, foundry inside it
+		LinkReferences linkReferences `json:"linkReferences"`
+		Networks       map[string]struct {
+			Address string `json:"address"`
+		} `json:"networks"`
+	}
+	if err := json.Unmarshal(b, &artifact); err != nil {
+		return nil, err
+	}
+	contractABI, err := parseABI(artifact.ABI)
+	if err != nil {
+		return nil, err
+	}
+	r := &contractArtifact{name: name, abi: contractABI, networks: make(map[string]common.Address, len(artifact.Networks))}
+	if artifact.ContractName != "" {
+		r.name = artifact.ContractName
+	}
+	if len(artifact.Bytecode) != 0 {
+		// truffle and hardhat use a string, foundry an object
+		var s string
+		refs := artifact.LinkReferences
+		if err = json.Unmarshal(artifact.Bytecode, &s); err != nil {
+			var obj struct {
+				Object         string         `json:"object"`
+				LinkReferences linkReferences `json:"linkReferences"`
+			}
+			if err = json.Unmarshal(artifact.Bytecode, &obj); err != nil {
+				return nil, fmt.Errorf("invalid bytecode: %w", err)
+			}
+			s, refs = obj.Object, obj.LinkReferences
+		}
+		r.bytecode, r.unlinked = parseBytecode(s, refs, nil)
+	}
+	for chainID, n := range artifact.Networks {
+		if common.IsHexAddress(n.Address) {
+			r.networks[chainID] = common.HexToAddress(n.Address)
+		}
+	}
+	return r, nil
+}
+
+// parseCombinedJSON parses the output of solc --combined-json abi,bin
+func parseCombinedJSON(b []byte) ([]*contractArtifact, error) {
+	var combined struct {
+		Contracts map[string]struct {
+			ABI json.RawMessage `json:"abi"`
+			Bin string          `json:"bin"`
+		} `json:"contracts"`
+	}
+	if err := json.Unmarshal(b, &combined); err != nil {
+		return nil, err
+	}
+	// contracts are keyed by path:name. the path is dropped unless the name is ambiguous
+	names := make(map[string]int, len(combined.Contracts))
+	keys := make([]string, 0, len(combined.Contracts))
+	for key := range combined.Contracts {
+		names[key[strings.LastIndex(key, ":")+1:]]++
+		keys = append(keys, key)
+	}
+	r := make([]*contractArtifact, 0, len(combined.Contracts))
+	for key, c := range combined.Contracts {
+		if len(c.ABI) == 0 {
+			return nil, fmt.Errorf("%s: missing abi (use --combined-json abi,bin)", key)
+		}
+		contractABI, err := parseABI(c.ABI)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", key, err)
+		}
+		name := key[strings.LastIndex(key, ":")+1:]
+		if names[name] > 1 {
+			name = key
+		}
+		a := &contractArtifact{name: name, abi: contractABI}
+		a.bytecode, a.unlinked = parseBytecode(c.Bin, nil, keys)
+		r = append(r, a)
+	}
+	sort.Slice(r, func(i, j int) bool { return r[i].name < r[j].name })
+	return r, nil
+}
+
+// selectArtifact returns the named contract, the only contract, or asks for one when choose is set
+func selectArtifact(artifacts []*contractArtifact, name string, choose bool) (*contractArtifact, error) {
+	if len(artifacts) == 0 {
+		return nil, errNoContracts
+	}
+	if name != "" {
+		for _, i := range artifacts {
+			if i.name == name {
+				return i, nil
+			}
+		}
+		return nil, fmt.Errorf("%w: %s", errUnknownContract, name)
+	}
+	if len(artifacts) == 1 {
+		return artifacts[0], nil
+	}
+	if !choose {
+		return nil, errMultipleContracts
+	}
+	choices := make([]prompt.Suggest, 0, len(artifacts))
+	for _, i := range artifacts {
+		desc := fmt.Sprintf("%d methods, %d events", len(i.abi.Methods), len(i.abi.Events))
+		if i.bytecode != nil {
+			desc += ", bytecode"
+		} else if len(i.unlinked) != 0 {
+			desc += ", unlinked bytecode"
+		}
+		choices = append(choices, prompt.Suggest{Text: i.name, Description: desc})
+	}
+	fmt.Printf("found %d contracts\n", len(artifacts))
+	c, ok := inputMultiChoice("contract (%s): ", artifacts[0].name, choices, showSuggestions)
+	if !ok {
+		return nil, errAborted
+	}
+	for _, i := range artifacts {
+		if i.name == c {
+			return i, nil
+		}
+	}
+	return nil, errUnknownContract
+}
+
+// loadArtifact reads the contract from an abi or artifact file
+func loadArtifact(fn, name string, choose bool) (*contractArtifact, error) {
+	artifacts, err := readArtifacts(fn)
+	if err != nil {
+		return nil, err
+	}
+	return selectArtifact(artifacts, name, choose)
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"errors"
+	"math/big"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+func TestReadArtifacts(t *testing.T) {
+	type expected struct {
+		name     string
+		bytecode string
+		unlinked []string
+		events   int
+	}
+	for _, i := range []struct {
+		file      string
+		artifacts []expected
+	}{
+		{"abi.json", []expected{{name: "abi", events: 1}}},
+		{"solc.json", []expected{
+			{name: "Counter", bytecode: "0x6080604052", events: 1},
+			{name: "Lib", bytecode: "0x6080"},
+			{name: "Main", unlinked: []string{"contracts/Lib.sol:Lib"}},
+		}},
+		{"truffle.json", []expected{{name: "Counter", bytecode: "0x6080604052", events: 1}}},
+		{"truffle-unlinked.json", []expected{{name: "Main", unlinked: []string{"Lib"}}}},
+		{"hardhat.json", []expected{{name: "Counter", bytecode: "0x6080604052", events: 1}}},
+		{"hardhat-unlinked.json", []expected{{name: "Main", unlinked: []string{"contracts/Lib.sol:Lib"}}}},
+		{"foundry.json", []expected{{name: "foundry", bytecode: "0x6080604052", events: 1}}},
+		{"foundry-unlinked.json", []expected{{name: "foundry-unlinked", unlinked: []string{"contracts/Lib.sol:Lib"}}}},
+	} {
+		artifacts, err := readArtifacts(filepath.Join("testdata", "artifacts", i.file))
+		if err != nil {
+			t.Errorf("%s: %s", i.file, err)
+			continue
+		}
+		if len(artifacts) != len(i.artifacts) {
+			t.Errorf("%s: expecting %d contracts, got %d", i.file, len(i.artifacts), len(artifacts))
+			continue
+		}
+		for n, a := range artifacts {
+			e := i.artifacts[n]
+			var bytecode string
+			if a.bytecode != nil {
+				bytecode = hexutil.Encode(a.bytecode)
+			}
+			if a.name != e.name || bytecode != e.bytecode || !reflect.DeepEqual(a.unlinked, e.unlinked) || len(a.abi.Events) != e.events {
+				t.Errorf("%s: expecting %+v, got name=%s bytecode=%s unlinked=%v events=%d", i.file, e, a.name, bytecode, a.unlinked, len(a.abi.Events))
+			}
+		}
+	}
+	if _, err := readArtifacts(filepath.Join("testdata", "artifacts", "unknown.json")); !errors.Is(err, errUnknownABIFormat) {
+		t.Errorf("expecting %v, got %v", errUnknownABIFormat, err)
+	}
+}
+
+func TestArtifactNetworks(t *testing.T) {
+	a, err := loadArtifact(filepath.Join("testdata", "artifacts", "truffle.json"), "", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if addr, ok := a.networkAddress(big.NewInt(5777)); !ok || addr.Hex() != "0x1111111111111111111111111111111111111111" {
+		t.Errorf("unexpected address on 5777: %s", addr.Hex())
+	}
+	if _, ok := a.networkAddress(big.NewInt(1)); ok {
+		t.Error("empty address on chain 1")
+	}
+}
+
+func TestReadBytecode(t *testing.T) {
+	b, err := readBytecode(filepath.Join("testdata", "artifacts", "solc.json"), "Counter")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hexutil.Encode(b) != "0x6080604052" {
+		t.Errorf("unexpected bytecode %x", b)
+	}
+	for _, i := range []struct{ file, name string }{
+		{"solc.json", "Main"},
+		{"hardhat-unlinked.json", "Main"},
+		{"foundry-unlinked.json", "foundry-unlinked"},
+	} {
+		_, err = readBytecode(filepath.Join("testdata", "artifacts", i.file), i.name)
+		if !errors.Is(err, errUnlinkedBytecode) || !strings.Contains(err.Error(), "contracts/Lib.sol:Lib") {
+			t.Errorf("%s: expecting the unlinked library, got %v", i.file, err)
+		}
+	}
+	if _, err = readBytecode(filepath.Join("testdata", "artifacts", "solc.json"), "Other"); !errors.Is(err, errUnknownContract) {
+		t.Errorf("expecting %v, got %v", errUnknownContract, err)
+	}
+}
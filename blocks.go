@@ -0,0 +1,196 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+const blockUsage = "number, latest, safe, finalized, earliest, -N for N blocks before the latest, " +
+	"a date (2006-01-02, 2006-01-02 15:04, rfc 3339, local time without a zone) or a duration ago (24h ago, 7d ago)"
+
+var blockTags = []string{"latest", "safe", "finalized", "earliest"}
+
+// dateLayouts are the accepted date formats, in local time when they don't have a zone
+var dateLayouts = []string{
+	time.RFC3339,
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"2006-01-02T15:04",
+	"2006-01-02 15:04",
+	"2006-01-02",
+}
+
+var errInvalidBlock = errors.New("invalid block")
+
+// resolveBlock returns the block number of the block spec. dates and durations resolve to the
+// first block at or after the time, or with last set to the last block at or before it
+func resolveBlock(ctx context.Context, cl backend, spec string, last bool) (uint64, error) {
+	spec = strings.ToLower(strings.TrimSpace(spec))
+	for _, i := range blockTags {
+		if spec == i {
+			h, err := cl.HeaderByTag(ctx, spec)
+			if err != nil {
+				return 0, fmt.Errorf("can't get the %s block: %w", spec, err)
+			}
+			return h.Number.Uint64(), nil
+		}
+	}
+	if n, err := strconv.ParseInt(spec, 10, 64); err == nil {
+		if n >= 0 {
+			return uint64(n), nil
+		}
+		h, err := cl.HeaderByNumber(ctx, nil)
+		if err != nil {
+			return 0, fmt.Errorf("can't get the last block: %w", err)
+		}
+		// offsets before the first block start at the first block
+		back := uint64(-n)
+		if back > h.Number.Uint64() {
+			return 0, nil
+		}
+		return h.Number.Uint64() - back, nil
+	}
+	t, err := parseBlockTime(spec)
+	if err != nil {
+		return 0, err
+	}
+	n, err := blockAtTime(ctx, cl, t, last)
+	if err != nil {
+		return 0, err
+	}
+	infof("%s is block %d\n", t.Format(time.RFC3339), n)
+	return n, nil
+}
+
+func parseBlockTime(spec string) (time.Time, error) {
+	if strings.HasSuffix(spec, " ago") {
+		d, err := parseAgo(strings.TrimSpace(strings.TrimSuffix(spec, " ago")))
+		if err != nil {
+			return time.Time{}, fmt.Errorf("%w: %s: %s", errInvalidBlock, spec, err)
+		}
+		return time.Now().Add(-d), nil
+	}
+	for _, i := range dateLayouts {
+		if t, err := time.ParseInLocation(i, spec, time.Local); err == nil {
+			return t, nil
+		}
+		// zones are parsed in upper case
+		if t, err := time.ParseInLocation(i, strings.ToUpper(spec), time.Local); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("%w: %s", errInvalidBlock, spec)
+}
+
+// parseAgo parses a duration, also accepting days
+func parseAgo(s string) (time.Duration, error) {
+	if strings.HasSuffix(s, "d") {
+		days, err := strconv.ParseFloat(strings.TrimSuffix(s, "d"), 64)
+		if err != nil {
+			return 0, err
+		}
+		return time.Duration(days * float64(24*time.Hour)), nil
+	}
+	return time.ParseDuration(s)
+}
+
+// blockAtTime searches the first block mined at or after t, or with last set the last block mined
+// at or before t
+func blockAtTime(ctx context.Context, cl backend, t time.Time, last bool) (uint64, error) {
+	head, err := cl.HeaderByNumber(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("can't get the last block: %w", err)
+	}
+	var ts uint64
+	if t.Unix() > 0 {
+		ts = uint64(t.Unix())
+	} else if last && t.Unix() < 0 {
+		return 0, fmt.Errorf("no blocks before %s", t.Format(time.RFC3339))
+	}
+	if !last && ts > head.Time {
+		return 0, fmt.Errorf("no blocks after %s", t.Format(time.RFC3339))
+	}
+	header := func(n uint64) (*types.Header, error) {
+		h, err := cl.HeaderByNumber(ctx, new(big.Int).SetUint64(n))
+		if err != nil {
+			return nil, fmt.Errorf("can't get block %d: %w", n, err)
+		}
+		return h, nil
+	}
+	// first block after the time, or mined at the time if it's not the last
+	after := func(h *types.Header) bool {
+		if last {
+			return h.Time > ts
+		}
+		return h.Time >= ts
+	}
+	lo, hi := uint64(0), head.Number.Uint64()+1
+	for lo < hi {
+		mid := lo + (hi-lo)/2
+		h, err := header(mid)
+		if err != nil {
+			return 0, err
+		}
+		if after(h) {
+			hi = mid
+		} else {
+			lo = mid + 1
+		}
+	}
+	if !last {
+		return lo, nil
+	}
+	if lo == 0 {
+		return 0, fmt.Errorf("no blocks before %s", t.Format(time.RFC3339))
+	}
+	return lo - 1, nil
+}
+
+// blockRange resolves the start and end blocks of a range
+func blockRange(cl backend, from, to string) (uint64, uint64, error) {
+	ctx := context.Background()
+	start, err := resolveBlock(ctx, cl, from, false)
+	if err != nil {
+		return 0, 0, err
+	}
+	end, err := resolveBlock(ctx, cl, to, true)
+	if err != nil {
+		return 0, 0, err
+	}
+	if start > end {
+		return 0, 0, fmt.Errorf("start block %d is after the end block %d", start, end)
+	}
+	return start, end, nil
+}
+
+// inputBlockRange asks for the start and end blocks until they can be resolved
+func inputBlockRange(cl backend) (uint64, uint64, error) {
+	ctx := context.Background()
+	var r [2]uint64
+	for n, i := range []struct{ pr, def string }{{"start block (%s): ", "0"}, {"end block (%s): ", "latest"}} {
+		for {
+			v, ok := inputTextWithDefault(i.pr, i.def)
+			if !ok {
+				return 0, 0, errAborted
+			}
+			b, err := resolveBlock(ctx, cl, v, n == 1)
+			if err != nil {
+				fmt.Printf("%s\nexpecting %s\n", err, blockUsage)
+				continue
+			}
+			r[n] = b
+			break
+		}
+	}
+	if r[0] > r[1] {
+		return 0, 0, fmt.Errorf("start block %d is after the end block %d", r[0], r[1])
+	}
+	return r[0], r[1], nil
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testBlockTimes commits 10 blocks to a simulated chain, block n mined 11-n days ago. the genesis
+// block is from 1970
+func testBlockTimes(t *testing.T) (*simulatedBackend, []uint64) {
+	sim := newSimulatedBackend()
+	t.Cleanup(func() { sim.Close() })
+	now := time.Now()
+	times := []uint64{0}
+	for n := 1; n <= 10; n++ {
+		ts := now.Add(-time.Duration(11-n) * 24 * time.Hour).Unix()
+		// blocks are mined 10 seconds after their parent unless the time is adjusted
+		if err := sim.AdjustTime(time.Duration(ts-int64(times[n-1])-10) * time.Second); err != nil {
+			t.Fatal(err)
+		}
+		sim.Commit()
+		h, err := sim.HeaderByNumber(context.Background(), big.NewInt(int64(n)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if h.Time != uint64(ts) {
+			t.Fatalf("block %d: expecting time %d, got %d", n, ts, h.Time)
+		}
+		times = append(times, h.Time)
+	}
+	return sim, times
+}
+
+func TestResolveBlock(t *testing.T) {
+	sim, times := testBlockTimes(t)
+	at := func(n uint64, offset int64) string {
+		return time.Unix(int64(times[n])+offset, 0).Format(time.RFC3339)
+	}
+	local := time.Unix(int64(times[5]), 0).Format("2006-01-02 15:04:05")
+	for _, i := range []struct {
+		spec  string
+		last  bool
+		block uint64
+		err   string // the error instead of the block
+	}{
+		{spec: "latest", block: 10},
+		{spec: " Latest ", block: 10},
+		{spec: "safe", block: 10},
+		{spec: "finalized", block: 10},
+		{spec: "earliest", block: 0},
+		{spec: "3", block: 3},
+		{spec: "-1", block: 9},
+		{spec: "-10", block: 0},
+		{spec: "-100", block: 0},
+		{spec: at(5, 0), block: 5},
+		{spec: at(5, 0), last: true, block: 5},
+		{spec: at(5, 1), block: 6},
+		{spec: at(5, 1), last: true, block: 5},
+		{spec: at(5, -1), block: 5},
+		{spec: at(5, -1), last: true, block: 4},
+		{spec: local, block: 5},
+		{spec: "1960-01-01T00:00:00Z", block: 0},
+		{spec: "1960-01-01", last: true, err: "no blocks before 1960-01-01T00:00:00"},
+		{spec: at(10, 1), err: "no blocks after " + at(10, 1)},
+		{spec: at(10, 1), last: true, block: 10},
+		{spec: "10.5d ago", block: 1},
+		{spec: "10.5d ago", last: true, block: 0},
+		{spec: "3.5d ago", block: 8},
+		{spec: "84h ago", last: true, block: 7},
+		{spec: "12h ago", err: "no blocks after"},
+		{spec: "12h ago", last: true, block: 10},
+		{spec: "1x ago", err: errInvalidBlock.Error()},
+		{spec: "yesterday", err: errInvalidBlock.Error()},
+	} {
+		n, err := resolveBlock(context.Background(), sim, i.spec, i.last)
+		if i.err != "" {
+			if err == nil || !strings.Contains(err.Error(), i.err) {
+				t.Errorf("%q last=%v: expecting error %s, got %d %v", i.spec, i.last, i.err, n, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q last=%v: %s", i.spec, i.last, err)
+		} else if n != i.block {
+			t.Errorf("%q last=%v: expecting block %d, got %d", i.spec, i.last, i.block, n)
+		}
+	}
+	if _, err := resolveBlock(context.Background(), sim, "-", false); !errors.Is(err, errInvalidBlock) {
+		t.Errorf("expecting %v, got %v", errInvalidBlock, err)
+	}
+}
+
+func TestBlockRange(t *testing.T) {
+	sim, _ := testBlockTimes(t)
+	for _, i := range []struct {
+		from, to   string
+		start, end uint64
+		err        bool
+	}{
+		{from: "0", to: "latest", start: 0, end: 10},
+		{from: "-2", to: "-1", start: 8, end: 9},
+		{from: "3.5d ago", to: "12h ago", start: 8, end: 10},
+		{from: "10.5d ago", to: "10.5d ago", start: 1, err: true},
+		{from: "latest", to: "earliest", err: true},
+	} {
+		start, end, err := blockRange(sim, i.from, i.to)
+		if i.err {
+			if err == nil {
+				t.Errorf("%s-%s: expecting an error, got %d-%d", i.from, i.to, start, end)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s-%s: %s", i.from, i.to, err)
+		} else if start != i.start || end != i.end {
+			t.Errorf("%s-%s: expecting %d-%d, got %d-%d", i.from, i.to, i.start, i.end, start, end)
+		}
+	}
+}
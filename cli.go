@@ -0,0 +1,1207 @@
+package main
+
+import (
+	"crypto/ecdsa"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/accounts/keystore"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// annotationConsoleOnly marks commands that only make sense in the interactive console
+const annotationConsoleOnly = "console_only"
+
+var consoleOnly = map[string]string{annotationConsoleOnly: "true"}
+
+// annotationOffline marks commands that run without connecting to the chain
+const annotationOffline = "offline"
+
+var offline = map[string]string{annotationOffline: "true"}
+
+// annotationProfileSetting marks flags that set a profile setting. given on the command line
+// they take precedence over the profile
+const annotationProfileSetting = "profile_setting"
+
+// markProfileSettings marks the flags that set profile settings
+func markProfileSettings(f *pflag.FlagSet, names ...string) {
+	for _, i := range names {
+		if err := f.SetAnnotation(i, annotationProfileSetting, []string{"true"}); err != nil {
+			panic(err)
+		}
+	}
+}
+
+var (
+	errConsoleOnly      = errors.New("command only available in the interactive console")
+	errUnknownMethod    = errors.New("unknown method")
+	errUnknownEventName = errors.New("unknown event")
+	errNotImplemented   = errors.New("not implemented")
+)
+
+var rootFlags struct {
+	url            string
+	address        string
+	abiFile        string
+	contract       string
+	keyFile        string
+	passwordFile   string
+	keystoreDir    string
+	account        string
+	mnemonicFile   string
+	externalSigner string
+	hdPath         string
+	hdIndex        uint32
+	profile        string
+	configFile     string
+	chainID        uint64
+	simulated      bool
+	bytecodeFile   string
+	constructor    []string
+}
+
+// interactive is set when the commands are running inside the console
+var interactive bool
+
+func newRootCommand() *cobra.Command {
+	cobra.EnableCommandSorting = false
+	r := &cobra.Command{
+		Use:   "scui [client_url address abi_file]",
+		Short: "ethereum smart contract interface",
+		Long: "ethereum smart contract interface\n\n" +
+			"without a command an interactive console is started",
+		Args:              legacyArgs,
+		SilenceUsage:      true,
+		PersistentPreRunE: preRun,
+		RunE:              runConsole,
+	}
+	pf := r.PersistentFlags()
+	pf.StringVar(&rootFlags.url, "rpc", "", "client url")
+	pf.Uint64Var(&rootFlags.chainID, "chain-id", 0, "refuse to send transactions if the endpoint isn't on this chain")
+	pf.StringVar(&rootFlags.address, "address", "", "contract address")
+	pf.StringVar(&rootFlags.abiFile, "abi", "", "contract abi or artifact file (solc combined-json, truffle, hardhat, foundry)")
+	pf.StringVar(&rootFlags.contract, "contract", "", "contract to use when the abi file has several")
+	pf.StringVar(&rootFlags.keyFile, "key", "", "sign with the key in the file")
+	pf.StringVar(&rootFlags.passwordFile, "password-file", "", "password to decrypt the key file or the keystore account")
+	pf.StringVar(&rootFlags.keystoreDir, "keystore", "", "sign with an account of the keystore directory")
+	pf.StringVar(&rootFlags.account, "account", "", "keystore or external signer account address")
+	pf.StringVar(&rootFlags.mnemonicFile, "mnemonic-file", "", "sign with an account derived from the bip-39 mnemonic in the file (--password-file holds the passphrase)")
+	pf.StringVar(&rootFlags.hdPath, "hd-path", defaultHDPath, "derivation path of the mnemonic accounts")
+	pf.Uint32Var(&rootFlags.hdIndex, "hd-index", 0, "index of the mnemonic account")
+	pf.StringVar(&rootFlags.externalSigner, "external-signer", "", "sign with a clef compatible signer at the ipc path or url")
+	pf.StringVar(&rootFlags.profile, "profile", "", "start from the profile in the config file")
+	pf.StringVar(&rootFlags.configFile, "config", defaultConfigFile(), "config file")
+	pf.BoolVar(&rootFlags.simulated, "simulated", false, "use an in-process simulated chain")
+	pf.StringVar(&rootFlags.bytecodeFile, "bytecode", "", "deploy the contract from the bytecode file instead of the artifact bytecode (simulated chain)")
+	pf.StringArrayVar(&rootFlags.constructor, "constructor-arg", nil, "constructor argument (name=value)")
+	pf.VarP(outputFormatValue{&outputFormat}, "output", "o", "output format ("+strings.Join(outputFormats, ", ")+")")
+	markProfileSettings(pf, "output")
+	r.AddCommand(
+		newConstantCommand(),
+		newTransactCommand(),
+		newDeployCommand(),
+		newEventsCommand(),
+		newSignFileCommand(),
+		newBroadcastCommand(),
+		newTxCommand(),
+		newContractsCommand(),
+		newConfigCommand(),
+		newSignerCommand(),
+		newSettingsCommand(),
+		newChainCommand(),
+	)
+	return r
+}
+
+func legacyArgs(cmd *cobra.Command, args []string) error {
+	if len(args) != 0 && len(args) != 3 {
+		return fmt.Errorf("expecting 0 or 3 arguments, got %d", len(args))
+	}
+	return nil
+}
+
+func preRun(cmd *cobra.Command, args []string) error {
+	switch cmd.Name() {
+	case "help", cobra.ShellCompRequestCmd, cobra.ShellCompNoDescRequestCmd:
+		return nil
+	}
+	if cmd.Annotations[annotationConsoleOnly] != "" {
+		return errConsoleOnly
+	}
+	interactive = !cmd.HasParent()
+	if cmd.Annotations[annotationOffline] != "" {
+		return loadOfflineSigner()
+	}
+	if !cmd.HasParent() && len(args) == 3 {
+		rootFlags.url, rootFlags.address, rootFlags.abiFile = args[0], args[1], args[2]
+	}
+	p, err := flagsProfile()
+	if err != nil {
+		return err
+	}
+	if err = startSession(p); err != nil {
+		return err
+	}
+	if rootFlags.abiFile != "" {
+		sess.sessionContract = sess.contracts[len(sess.contracts)-1]
+	}
+	if err = applyCommandSettings(cmd, p); err != nil {
+		return err
+	}
+	if sess.sim != nil {
+		infof("simulated chain with %d dev accounts\n", len(sess.sim.keys))
+		if err = sess.deploySimulated(rootFlags.bytecodeFile, rootFlags.constructor); err != nil {
+			return err
+		}
+	}
+	updateChainCommand(cmd.Root())
+	return nil
+}
+
+// applyCommandSettings applies the profile settings. the profile setting flags given on the
+// command line take precedence over them
+func applyCommandSettings(cmd *cobra.Command, p *profile) error {
+	changed := make(map[string]string, 4)
+	cmd.Flags().Visit(func(f *pflag.Flag) {
+		if _, ok := f.Annotations[annotationProfileSetting]; ok {
+			changed[f.Name] = f.Value.String()
+		}
+	})
+	if err := applyProfileSettings(p); err != nil {
+		return err
+	}
+	for name, v := range changed {
+		if err := cmd.Flags().Set(name, v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// loadOfflineSigner loads only the signer of the profile, without opening a session
+func loadOfflineSigner() error {
+	p, err := flagsProfile()
+	if err != nil {
+		return err
+	}
+	if p.Signer == nil {
+		return nil
+	}
+	txSigner, err = loadProfileSigner(p.Signer)
+	return err
+}
+
+// updateChainCommand shows the chain commands only for simulated chains
+func updateChainCommand(root *cobra.Command) {
+	if chainCmd, _, err := root.Find([]string{"chain"}); err == nil {
+		chainCmd.Hidden = sess.sim == nil
+	}
+}
+
+// flagsProfile returns the profile selected with --profile, overridden by the other flags
+func flagsProfile() (*profile, error) {
+	p := &profile{}
+	if rootFlags.profile != "" {
+		cfg, err := readConfig(rootFlags.configFile)
+		if err != nil {
+			return nil, fmt.Errorf("can't read config: %w", err)
+		}
+		if p, err = cfg.profile(rootFlags.profile); err != nil {
+			return nil, err
+		}
+		profileName = rootFlags.profile
+	}
+	if rootFlags.url != "" {
+		p.URL = rootFlags.url
+	}
+	if rootFlags.simulated {
+		p.Simulated = true
+	}
+	if rootFlags.chainID != 0 {
+		p.ChainID = rootFlags.chainID
+	}
+	if rootFlags.abiFile != "" {
+		p.Contracts = append(p.Contracts, profileContract{
+			ABI:      rootFlags.abiFile,
+			Contract: rootFlags.contract,
+			Address:  rootFlags.address,
+		})
+	}
+	if rootFlags.keyFile != "" {
+		p.Signer = &profileSigner{Key: rootFlags.keyFile, PasswordFile: rootFlags.passwordFile}
+	} else if rootFlags.mnemonicFile != "" {
+		p.Signer = &profileSigner{
+			Mnemonic:     rootFlags.mnemonicFile,
+			HDPath:       rootFlags.hdPath,
+			Index:        rootFlags.hdIndex,
+			PasswordFile: rootFlags.passwordFile,
+		}
+	} else if rootFlags.keystoreDir != "" {
+		p.Signer = &profileSigner{Keystore: rootFlags.keystoreDir, Account: rootFlags.account, PasswordFile: rootFlags.passwordFile}
+	} else if rootFlags.externalSigner != "" {
+		p.Signer = &profileSigner{External: rootFlags.externalSigner, Account: rootFlags.account}
+	}
+	return p, nil
+}
+
+// loadKeySigner reads the key file, asking for the password of encrypted keys in the console
+func loadKeySigner(keyFile, passwordFile string) (*keySigner, error) {
+	keyFile, err := filepath.Abs(keyFile)
+	if err != nil {
+		return nil, err
+	}
+	if passwordFile != "" {
+		if passwordFile, err = filepath.Abs(passwordFile); err != nil {
+			return nil, err
+		}
+	}
+	key, err := readKeyFile(keyFile, passwordFile)
+	if err != nil {
+		return nil, err
+	}
+	r := newKeySigner(key)
+	r.keyFile, r.passwordFile = keyFile, passwordFile
+	return r, nil
+}
+
+func readKeyFile(keyFile, passwordFile string) (*ecdsa.PrivateKey, error) {
+	b, err := ioutil.ReadFile(keyFile)
+	if err != nil {
+		return nil, err
+	}
+	var password string
+	if passwordFile != "" {
+		pb, err := ioutil.ReadFile(passwordFile)
+		if err != nil {
+			return nil, err
+		}
+		password = strings.TrimRight(string(pb), "\r\n")
+	} else if !strings.HasPrefix(strings.TrimSpace(string(b)), "{") {
+		return crypto.HexToECDSA(strings.TrimSpace(string(b)))
+	} else if !interactive {
+		return nil, errors.New("encrypted key file needs a password file")
+	} else {
+		fmt.Printf("key file %s is encrypted\n", keyFile)
+		if password, err = inputPassword(); err != nil {
+			return nil, err
+		}
+		fmt.Println()
+	}
+	k, err := keystore.DecryptKey(b, password)
+	if err != nil {
+		return nil, err
+	}
+	return k.PrivateKey, nil
+}
+
+func newConstantCommand() *cobra.Command {
+	r := &cobra.Command{
+		Use:               "constant <method>",
+		Aliases:           []string{"call"},
+		Short:             "make a call to a constant method",
+		Args:              cobra.ExactArgs(1),
+		ValidArgsFunction: methodsCompletion(true),
+		RunE:              runConstant,
+	}
+	r.Flags().StringArray("arg", nil, "method argument (name=value)")
+	return r
+}
+
+func newTransactCommand() *cobra.Command {
+	r := &cobra.Command{
+		Use:               "transact <method>",
+		Aliases:           []string{"send"},
+		Short:             "make a transaction to a method",
+		Args:              cobra.ExactArgs(1),
+		ValidArgsFunction: methodsCompletion(false),
+		RunE:              runTransact,
+	}
+	f := r.Flags()
+	f.StringArray("arg", nil, "method argument (name=value)")
+	f.String("value", "", "amount to send with the transaction (payable methods)")
+	f.String("gas-price", "", "gas price, sending a legacy transaction (profile default or estimated if empty)")
+	f.String("max-fee", "", "max fee per gas of dynamic fee transactions (estimated if empty)")
+	f.String("priority-fee", "", "max priority fee per gas of dynamic fee transactions (estimated if empty)")
+	f.Uint64("gas-limit", 0, "gas limit (profile default or estimated if zero)")
+	f.Bool("dry-run", false, "show the transaction preview without sending it")
+	f.BoolP("yes", "y", false, "send the transaction without asking for confirmation")
+	f.String("nonce", "", "nonce (pending nonce if empty)")
+	f.String("unsigned", "", "save the transaction unsigned to the file, to be signed offline with sign-file")
+	f.String("from", "", "sender of the unsigned transaction when there's no signer")
+	f.BoolVar(&receiptConfig.wait, "wait", receiptConfig.wait, "wait for the transaction receipt")
+	f.IntVar(&receiptConfig.confirmations, "confirmations", receiptConfig.confirmations, "confirmations to wait for")
+	f.DurationVar(&receiptConfig.timeout, "timeout", receiptConfig.timeout, "time to wait for the receipt (0 to wait forever)")
+	markProfileSettings(f, "wait", "confirmations", "timeout")
+	return r
+}
+
+func newDeployCommand() *cobra.Command {
+	r := &cobra.Command{
+		Use:   "deploy [bytecode_file]",
+		Short: "deploy a new instance of the contract",
+		Long: "deploy a new instance of the contract\n\n" +
+			"the bytecode file can be hex or a compiler artifact. without it the bytecode in the abi artifact is used.\n" +
+			"the session switches to the deployed contract",
+		Args: cobra.MaximumNArgs(1),
+		RunE: runDeploy,
+	}
+	f := r.Flags()
+	f.StringArray("arg", nil, "constructor argument (name=value)")
+	f.String("value", "", "amount to send with the transaction (payable constructor)")
+	f.String("gas-price", "", "gas price, sending a legacy transaction (profile default or estimated if empty)")
+	f.String("max-fee", "", "max fee per gas of dynamic fee transactions (estimated if empty)")
+	f.String("priority-fee", "", "max priority fee per gas of dynamic fee transactions (estimated if empty)")
+	f.Uint64("gas-limit", 0, "gas limit (profile default or estimated if zero)")
+	f.Bool("dry-run", false, "show the transaction preview without sending it")
+	f.BoolP("yes", "y", false, "send the transaction without asking for confirmation")
+	f.String("nonce", "", "nonce (pending nonce if empty)")
+	f.String("unsigned", "", "save the transaction unsigned to the file, to be signed offline with sign-file")
+	f.String("from", "", "sender of the unsigned transaction when there's no signer")
+	f.IntVar(&receiptConfig.confirmations, "confirmations", receiptConfig.confirmations, "confirmations to wait for")
+	f.DurationVar(&receiptConfig.timeout, "timeout", receiptConfig.timeout, "time to wait for the receipt (0 to wait forever)")
+	markProfileSettings(f, "confirmations", "timeout")
+	return r
+}
+
+func newSignFileCommand() *cobra.Command {
+	r := &cobra.Command{
+		Use:   "sign-file [unsigned_file]",
+		Short: "sign an unsigned transaction file offline",
+		Long: "sign an unsigned transaction file offline\n\n" +
+			"the file is written by transact or deploy with --unsigned. the signed transaction is saved as hex.\n" +
+			"outside the console no connection is made, --abi is only used to decode the calldata",
+		Args:        cobra.MaximumNArgs(1),
+		Annotations: offline,
+		RunE:        runSignFile,
+	}
+	r.Flags().String("out", "", "signed transaction file (the unsigned file with a .signed extension if empty)")
+	return r
+}
+
+func newBroadcastCommand() *cobra.Command {
+	r := &cobra.Command{
+		Use:   "broadcast [signed_file|hex]",
+		Short: "send a signed raw transaction",
+		Args:  cobra.MaximumNArgs(1),
+		RunE:  runBroadcast,
+	}
+	f := r.Flags()
+	f.BoolVar(&receiptConfig.wait, "wait", receiptConfig.wait, "wait for the transaction receipt")
+	f.IntVar(&receiptConfig.confirmations, "confirmations", receiptConfig.confirmations, "confirmations to wait for")
+	f.DurationVar(&receiptConfig.timeout, "timeout", receiptConfig.timeout, "time to wait for the receipt (0 to wait forever)")
+	markProfileSettings(f, "wait", "confirmations", "timeout")
+	return r
+}
+
+func newTxCommand() *cobra.Command {
+	r := &cobra.Command{
+		Use:         "tx",
+		Short:       "manage the transactions sent in the session",
+		Annotations: consoleOnly,
+	}
+	r.AddCommand(
+		&cobra.Command{
+			Use:         "list",
+			Short:       "list the transactions sent in the session",
+			Annotations: consoleOnly,
+			RunE:        cmdTxList,
+		},
+		&cobra.Command{
+			Use:         "speed-up",
+			Short:       "resend a pending transaction with higher fees",
+			Annotations: consoleOnly,
+			RunE:        cmdTxSpeedUp,
+		},
+		&cobra.Command{
+			Use:         "cancel",
+			Short:       "replace a pending transaction with a 0 value self-transfer",
+			Annotations: consoleOnly,
+			RunE:        cmdTxCancel,
+		},
+		&cobra.Command{
+			Use:         "nonce",
+			Short:       "set the nonce of the next transaction",
+			Annotations: consoleOnly,
+			RunE:        cmdTxNonce,
+		},
+	)
+	return r
+}
+
+const filterUsage = "indexed field filter (name=value, or name=@file for the values of a file). " +
+	"repeated fields match any of their values"
+
+func newEventsCommand() *cobra.Command {
+	r := &cobra.Command{
+		Use:   "events",
+		Short: "filter/watch events",
+	}
+	list := &cobra.Command{
+		Use:               "list <event|all>",
+		Short:             "list event",
+		Args:              cobra.ExactArgs(1),
+		ValidArgsFunction: eventsOrAllCompletion,
+		RunE:              runListEvents,
+	}
+	list.Flags().StringArray("filter", nil, filterUsage)
+	list.Flags().StringArray("event", nil, "event listed with all (every event if not set)")
+	list.Flags().String("from", "0", "start block ("+blockUsage+")")
+	list.Flags().String("to", "latest", "end block")
+	export := &cobra.Command{
+		Use:               "export <event>",
+		Short:             "export events to a csv, json lines or parquet file",
+		Args:              cobra.ExactArgs(1),
+		ValidArgsFunction: eventsCompletion,
+		RunE:              runExportEvents,
+	}
+	export.Flags().StringArray("filter", nil, filterUsage)
+	export.Flags().String("from", "0", "start block ("+blockUsage+")")
+	export.Flags().String("to", "latest", "end block")
+	export.Flags().String("format", exportCSV, "file format ("+strings.Join(exportFormats, ", ")+")")
+	export.Flags().String("out", "", "output file (<event>.<format> if empty)")
+	watch := &cobra.Command{
+		Use:               "watch <event|all>",
+		Short:             "watch event",
+		Args:              cobra.ExactArgs(1),
+		ValidArgsFunction: eventsOrAllCompletion,
+		RunE:              runWatchEvents,
+	}
+	watch.Flags().StringArray("filter", nil, filterUsage)
+	watch.Flags().StringArray("event", nil, "event watched with all (every event if not set)")
+	watch.Flags().BoolVar(&watchConfig.poll, "poll", watchConfig.poll, "poll for new logs even when the endpoint supports subscriptions")
+	watch.Flags().DurationVar(&watchConfig.pollInterval, "poll-interval", watchConfig.pollInterval, "interval between polls when subscriptions aren't available")
+	watch.Flags().IntVar(&watchConfig.confirmations, "confirmations", watchConfig.confirmations, "blocks on top of an event before it's confirmed (1 for the head block)")
+	markProfileSettings(watch.Flags(), "poll", "poll-interval", "confirmations")
+	r.AddCommand(list, export, watch)
+	return r
+}
+
+func newContractsCommand() *cobra.Command {
+	r := &cobra.Command{
+		Use:   "contracts",
+		Short: "manage the session contracts",
+	}
+	r.AddCommand(
+		&cobra.Command{
+			Use:   "list",
+			Short: "list the contracts",
+			Args:  cobra.NoArgs,
+			RunE:  cmdContractsList,
+		},
+		&cobra.Command{
+			Use:         "add",
+			Short:       "add a contract",
+			Annotations: consoleOnly,
+			RunE:        cmdContractsAdd,
+		},
+		&cobra.Command{
+			Use:               "remove <name>",
+			Short:             "remove a contract",
+			Args:              cobra.ExactArgs(1),
+			Annotations:       consoleOnly,
+			ValidArgsFunction: contractsCompletion,
+			RunE:              cmdContractsRemove,
+		},
+		&cobra.Command{
+			Use:               "select <name>",
+			Short:             "select the contract used by the commands",
+			Args:              cobra.ExactArgs(1),
+			Annotations:       consoleOnly,
+			ValidArgsFunction: contractsCompletion,
+			RunE:              cmdContractsSelect,
+		},
+	)
+	return r
+}
+
+func newConfigCommand() *cobra.Command {
+	r := &cobra.Command{
+		Use:   "config",
+		Short: "manage the configuration profiles",
+	}
+	r.AddCommand(
+		&cobra.Command{
+			Use:   "show",
+			Short: "show the profiles and the current session",
+			Args:  cobra.NoArgs,
+			RunE:  cmdConfigShow,
+		},
+		&cobra.Command{
+			Use:         "save",
+			Short:       "save the current session as a profile",
+			Annotations: consoleOnly,
+			RunE:        cmdConfigSave,
+		},
+		&cobra.Command{
+			Use:               "load <profile>",
+			Short:             "replace the current session with a profile",
+			Args:              cobra.ExactArgs(1),
+			Annotations:       consoleOnly,
+			ValidArgsFunction: profilesCompletion,
+			RunE:              cmdConfigLoad,
+		},
+		&cobra.Command{
+			Use:               "edit <profile>",
+			Short:             "edit the connection and defaults of a profile",
+			Args:              cobra.ExactArgs(1),
+			Annotations:       consoleOnly,
+			ValidArgsFunction: profilesCompletion,
+			RunE:              cmdConfigEdit,
+		},
+	)
+	return r
+}
+
+func newSignerCommand() *cobra.Command {
+	r := &cobra.Command{
+		Use:         "signer",
+		Short:       "configure signer",
+		Annotations: consoleOnly,
+	}
+	r.AddCommand(
+		&cobra.Command{
+			Use:         "key",
+			Short:       "sign with a key",
+			Annotations: consoleOnly,
+			RunE:        cmdConfigSignerKey,
+		},
+		&cobra.Command{
+			Use:         "keystore",
+			Short:       "sign with an account of a keystore directory",
+			Annotations: consoleOnly,
+			RunE:        cmdConfigSignerKeystore,
+		},
+		&cobra.Command{
+			Use:         "mnemonic",
+			Short:       "sign with an account derived from a bip-39 mnemonic",
+			Annotations: consoleOnly,
+			RunE:        cmdConfigSignerMnemonic,
+		},
+		&cobra.Command{
+			Use:         "account",
+			Short:       "switch to another account of the mnemonic",
+			Annotations: consoleOnly,
+			RunE:        cmdConfigSignerAccount,
+		},
+		&cobra.Command{
+			Use:         "external",
+			Short:       "sign with a clef compatible external signer",
+			Annotations: consoleOnly,
+			RunE:        cmdConfigSignerExternal,
+		},
+		&cobra.Command{
+			Use:         "ledger",
+			Short:       "sign with ledger",
+			Annotations: consoleOnly,
+			RunE:        cmdConfigSignerLedger,
+		},
+	)
+	return r
+}
+
+func newSettingsCommand() *cobra.Command {
+	r := &cobra.Command{
+		Use:         "settings",
+		Short:       "configure settings",
+		Annotations: consoleOnly,
+	}
+	r.AddCommand(
+		&cobra.Command{
+			Use:         "receipt",
+			Short:       "configure waiting for transaction receipts",
+			Annotations: consoleOnly,
+			RunE:        cmdSettingsReceipt,
+		},
+		&cobra.Command{
+			Use:         "watch",
+			Short:       "configure confirmations and polling when watching events",
+			Annotations: consoleOnly,
+			RunE:        cmdSettingsWatch,
+		},
+		&cobra.Command{
+			Use:         "output",
+			Short:       "configure the output format",
+			Annotations: consoleOnly,
+			RunE:        cmdSettingsOutput,
+		},
+	)
+	return r
+}
+
+func newChainCommand() *cobra.Command {
+	r := &cobra.Command{
+		Use:         "chain",
+		Short:       "control the simulated chain",
+		Annotations: consoleOnly,
+		Hidden:      true,
+	}
+	r.AddCommand(
+		&cobra.Command{
+			Use:         "mine",
+			Short:       "mine blocks",
+			Annotations: consoleOnly,
+			RunE:        cmdChainMine,
+		},
+		&cobra.Command{
+			Use:         "time",
+			Short:       "move the time forward",
+			Annotations: consoleOnly,
+			RunE:        cmdChainTime,
+		},
+		&cobra.Command{
+			Use:         "autocommit",
+			Short:       "mine transactions as soon as they are sent",
+			Annotations: consoleOnly,
+			RunE:        cmdChainAutoCommit,
+		},
+		&cobra.Command{
+			Use:         "accounts",
+			Short:       "list the dev accounts and choose the signer",
+			Annotations: consoleOnly,
+			RunE:        cmdChainAccounts,
+		},
+	)
+	return r
+}
+
+// completionABI returns the session abi or reads it from the flags when completing from the shell
+func completionABI() *abi.ABI {
+	if sess.sessionContract != nil {
+		return sess.abi
+	}
+	if rootFlags.abiFile == "" {
+		return nil
+	}
+	r, err := loadArtifact(rootFlags.abiFile, rootFlags.contract, false)
+	if err != nil {
+		return nil
+	}
+	return r.abi
+}
+
+func profilesCompletion(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	if len(args) != 0 {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	cfg, err := readConfig(rootFlags.configFile)
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	r := make([]string, 0, len(cfg.Profiles))
+	for _, name := range cfg.profileNames() {
+		p := cfg.Profiles[name]
+		desc := p.URL
+		if p.Simulated {
+			desc = "simulated chain"
+		}
+		r = append(r, name+"\t"+desc)
+	}
+	return r, cobra.ShellCompDirectiveNoFileComp
+}
+
+func contractsCompletion(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	if len(args) != 0 {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	r := make([]string, 0, len(sess.contracts))
+	for _, i := range sess.contracts {
+		desc := "not deployed"
+		if i.address != (common.Address{}) {
+			desc = i.address.Hex()
+		}
+		r = append(r, i.name+"\t"+desc)
+	}
+	return r, cobra.ShellCompDirectiveNoFileComp
+}
+
+func methodsCompletion(constant bool) func(*cobra.Command, []string, string) ([]string, cobra.ShellCompDirective) {
+	return func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		contractABI := completionABI()
+		if len(args) != 0 || contractABI == nil {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+		r := make([]string, 0, len(contractABI.Methods))
+		for name, m := range contractABI.Methods {
+			if m.IsConstant() == constant {
+				r = append(r, name+"\t"+m.String())
+			}
+		}
+		sort.Strings(r)
+		return r, cobra.ShellCompDirectiveNoFileComp
+	}
+}
+
+func eventsCompletion(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	contractABI := completionABI()
+	if len(args) != 0 || contractABI == nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	r := make([]string, 0, len(contractABI.Events))
+	for name, e := range contractABI.Events {
+		r = append(r, name+"\t"+e.String())
+	}
+	sort.Strings(r)
+	return r, cobra.ShellCompDirectiveNoFileComp
+}
+
+// eventsOrAllCompletion adds the all argument, selecting every event, to the events
+func eventsOrAllCompletion(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	r, d := eventsCompletion(cmd, args, toComplete)
+	if len(args) != 0 {
+		return r, d
+	}
+	if contractABI := completionABI(); contractABI != nil {
+		if _, ok := contractABI.Events[allEvents]; ok {
+			return r, d
+		}
+	}
+	return append([]string{allEvents + "\tevery event of the contract, unknown logs included"}, r...), d
+}
+
+func runConstant(cmd *cobra.Command, args []string) error {
+	name := args[0]
+	method, ok := sess.abi.Methods[name]
+	if !ok {
+		return errUnknownMethod
+	}
+	addr, err := sess.contract()
+	if err != nil {
+		return err
+	}
+	var margs []interface{}
+	if interactive {
+		fmt.Printf("constant call arguments:\n")
+		margs, err = inputArguments(method.Inputs, false)
+	} else {
+		margs, err = flagArguments(cmd, method.Inputs)
+	}
+	if err != nil {
+		return err
+	}
+	r, err := executeConstantMethod(sess.client, addr, sess.abi, name, margs)
+	if err != nil {
+		return fmt.Errorf("can't execute constant method \"%s\": %w", name, err)
+	}
+	if outputFormat != outputText {
+		printJSON(newCallOutput(&method, r))
+		return nil
+	}
+	fmt.Printf("returned:\n")
+	for n, i := range r {
+		fmt.Printf("  (%s) %v\n", method.Outputs[n].Type.String(), formatValue(i))
+	}
+	return nil
+}
+
+func runTransact(cmd *cobra.Command, args []string) error {
+	name := args[0]
+	method, ok := sess.abi.Methods[name]
+	if !ok {
+		return errUnknownMethod
+	}
+	addr, err := sess.contract()
+	if err != nil {
+		return err
+	}
+	var (
+		margs  []interface{}
+		params *transactParams
+	)
+	if interactive {
+		var from *common.Address
+		if txSigner == nil {
+			if from, err = inputUnsignedSender(); err != nil {
+				return err
+			}
+		}
+		fmt.Printf("transaction arguments:\n")
+		if margs, err = inputArguments(method.Inputs, false); err != nil {
+			return err
+		}
+		if params, err = inputTransactParams(sess.client, &method); err == nil {
+			params.from = from
+		}
+	} else {
+		if margs, err = flagArguments(cmd, method.Inputs); err != nil {
+			return err
+		}
+		params, err = flagTransactParams(cmd)
+	}
+	if err != nil {
+		return err
+	}
+	tx, err := executeTransactMethod(sess.client, addr, sess.abi, name, margs, params)
+	if errors.Is(err, errDryRun) || errors.Is(err, errUnsignedSaved) {
+		infof("%s\n", err)
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("can't send transaction to method %s: %w", name, err)
+	}
+	sess.recordTransaction(txSigner.address(), sess.name, name, sess.abi, tx)
+	showTransaction(sess.client, addr, sess.abi, name, tx)
+	return nil
+}
+
+func runDeploy(cmd *cobra.Command, args []string) error {
+	constructor := &sess.abi.Constructor
+	var (
+		bytecodeFile string
+		margs        []interface{}
+		params       *transactParams
+		err          error
+	)
+	if len(args) > 0 {
+		bytecodeFile = args[0]
+	}
+	bytecode := sess.bytecode
+	if interactive {
+		var from *common.Address
+		if txSigner == nil {
+			if from, err = inputUnsignedSender(); err != nil {
+				return err
+			}
+		}
+		if bytecodeFile == "" && bytecode != nil {
+			useArtifact, ok := inputYesNo("deploy the bytecode in the artifact? (%s): ", true)
+			if !ok {
+				return errAborted
+			}
+			if !useArtifact {
+				bytecode = nil
+			}
+		}
+		if bytecodeFile == "" && bytecode == nil {
+			if len(sess.unlinked) != 0 {
+				fmt.Printf("%s\n", unlinkedError(sess.unlinked))
+			}
+			p, err := filepath.Abs(".")
+			if err != nil {
+				return err
+			}
+			if bytecodeFile, err = inputFilename("bytecode file: ", p, true); err != nil {
+				return err
+			}
+		}
+		fmt.Printf("constructor arguments:\n")
+		if margs, err = inputArguments(constructor.Inputs, false); err != nil {
+			return err
+		}
+		if params, err = inputTransactParams(sess.client, constructor); err == nil {
+			params.from = from
+		}
+	} else {
+		if bytecodeFile == "" && bytecode == nil {
+			if len(sess.unlinked) != 0 {
+				return fmt.Errorf("%w, deploy the linked bytecode from a file", unlinkedError(sess.unlinked))
+			}
+			return errors.New("missing bytecode file")
+		}
+		if margs, err = flagArguments(cmd, constructor.Inputs); err != nil {
+			return err
+		}
+		params, err = flagTransactParams(cmd)
+	}
+	if err != nil {
+		return err
+	}
+	if bytecodeFile != "" {
+		if bytecode, err = readBytecode(bytecodeFile, sess.name); err != nil {
+			return fmt.Errorf("can't read bytecode: %w", err)
+		}
+	}
+	addr, tx, err := deployContract(sess.client, sess.abi, bytecode, margs, params)
+	if errors.Is(err, errDryRun) || errors.Is(err, errUnsignedSaved) {
+		infof("%s\n", err)
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("can't deploy contract: %w", err)
+	}
+	sess.recordTransaction(txSigner.address(), sess.name, "constructor", sess.abi, tx)
+	infof("transaction sent: %s\n", tx.Hash().Hex())
+	infof("waiting for receipt (%d confirmations, press ctrl-c to stop waiting)\n", receiptConfig.confirmations)
+	mt, err := waitMinedTransaction(sess.client, sess.abi, tx)
+	if err != nil {
+		return fmt.Errorf("stopped waiting for receipt (contract will be at %s): %w", addr.Hex(), err)
+	}
+	if mt.receipt.Status == types.ReceiptStatusSuccessful {
+		sess.address = addr
+	}
+	if outputFormat != outputText {
+		r := newTransactionOutput(&addr, sess.abi, "constructor", tx, mt)
+		if mt.receipt.Status == types.ReceiptStatusSuccessful {
+			r.ContractAddress = addr.Hex()
+		}
+		printJSON(r)
+		return nil
+	}
+	fmt.Print(formatReceipt(&addr, sess.abi, mt))
+	if mt.receipt.Status != types.ReceiptStatusSuccessful {
+		return errors.New("contract deployment failed")
+	}
+	fmt.Printf("contract deployed at %s\n", addr.Hex())
+	return nil
+}
+
+func runListEvents(cmd *cobra.Command, args []string) error {
+	query, _, names, err := selectEvents(cmd, args[0])
+	if err != nil {
+		return err
+	}
+	start, end, err := eventsBlockRange(cmd)
+	if err != nil {
+		return err
+	}
+	return listEvents(sess.client, sess.abi, query, names, start, end)
+}
+
+// eventsBlockRange returns the block range of the from and to flags, or asks for it
+func eventsBlockRange(cmd *cobra.Command) (uint64, uint64, error) {
+	if interactive {
+		return inputBlockRange(sess.client)
+	}
+	from, err := cmd.Flags().GetString("from")
+	if err != nil {
+		return 0, 0, err
+	}
+	to, err := cmd.Flags().GetString("to")
+	if err != nil {
+		return 0, 0, err
+	}
+	return blockRange(sess.client, from, to)
+}
+
+// selectEvents returns the query of the event, filtered by its indexed fields, or with the all
+// argument the query of the chosen events (every log of the contract if none is chosen). it also
+// returns a description of the events and whether there are several
+func selectEvents(cmd *cobra.Command, name string) (ethereum.FilterQuery, string, bool, error) {
+	addr, err := sess.contract()
+	if err != nil {
+		return ethereum.FilterQuery{}, "", false, err
+	}
+	if event, ok := sess.abi.Events[name]; ok {
+		var filters [][]interface{}
+		if interactive {
+			filters, err = inputFilters(event.Inputs)
+		} else {
+			if names, _ := cmd.Flags().GetStringArray("event"); len(names) > 0 {
+				return ethereum.FilterQuery{}, "", false, errors.New("events can only be chosen with all")
+			}
+			filters, err = flagFilters(cmd, event.Inputs)
+		}
+		if err != nil {
+			return ethereum.FilterQuery{}, "", false, fmt.Errorf("error parsing filter fields: %w", err)
+		}
+		query, err := eventQuery(addr, &event, filters)
+		return query, name + " events", false, err
+	} else if name != allEvents {
+		return ethereum.FilterQuery{}, "", false, errUnknownEventName
+	}
+	var names []string
+	if interactive {
+		names, err = inputEventNames(sess.abi)
+	} else {
+		if filters, _ := cmd.Flags().GetStringArray("filter"); len(filters) > 0 {
+			return ethereum.FilterQuery{}, "", false, errors.New("filters need a single event")
+		}
+		names, err = flagEventNames(cmd, sess.abi)
+	}
+	if err != nil {
+		return ethereum.FilterQuery{}, "", false, err
+	}
+	what := "all the events"
+	if len(names) > 0 {
+		what = strings.Join(names, ", ") + " events"
+	}
+	return contractEventsQuery(addr, sess.abi, names), what, true, nil
+}
+
+func runExportEvents(cmd *cobra.Command, args []string) error {
+	name := args[0]
+	event, ok := sess.abi.Events[name]
+	if !ok {
+		return errUnknownEventName
+	}
+	addr, err := sess.contract()
+	if err != nil {
+		return err
+	}
+	var (
+		filters     [][]interface{}
+		format, out string
+	)
+	if interactive {
+		if filters, err = inputFilters(event.Inputs); err != nil {
+			return fmt.Errorf("error parsing filter fields: %w", err)
+		}
+	} else if filters, err = flagFilters(cmd, event.Inputs); err != nil {
+		return fmt.Errorf("error parsing filter fields: %w", err)
+	}
+	start, end, err := eventsBlockRange(cmd)
+	if err != nil {
+		return err
+	}
+	if interactive {
+		var ok bool
+		if format, ok = inputMultiChoiceString("format (%s): ", exportCSV, exportFormats, showSuggestions); !ok {
+			return errAborted
+		}
+		if out, ok = inputTextWithDefault("file (%s): ", exportFileName(name, format)); !ok {
+			return errAborted
+		}
+	} else {
+		if format, err = cmd.Flags().GetString("format"); err != nil {
+			return err
+		}
+		if err = checkExportFormat(format); err != nil {
+			return err
+		}
+		if out, err = cmd.Flags().GetString("out"); err != nil {
+			return err
+		}
+		if out == "" {
+			out = exportFileName(name, format)
+		}
+	}
+	return exportEvents(sess.client, addr, sess.abi, name, filters, start, end, format, out)
+}
+
+func runWatchEvents(cmd *cobra.Command, args []string) error {
+	query, what, names, err := selectEvents(cmd, args[0])
+	if err != nil {
+		return err
+	}
+	return watchEvents(sess.client, sess.abi, query, what, names)
+}
+
+func flagArguments(cmd *cobra.Command, inputs abi.Arguments) ([]interface{}, error) {
+	values, err := cmd.Flags().GetStringArray("arg")
+	if err != nil {
+		return nil, err
+	}
+	return parseArguments(inputs, values)
+}
+
+func flagFilters(cmd *cobra.Command, inputs abi.Arguments) ([][]interface{}, error) {
+	values, err := cmd.Flags().GetStringArray("filter")
+	if err != nil {
+		return nil, err
+	}
+	return parseFilters(inputs, values)
+}
+
+func flagEventNames(cmd *cobra.Command, contractABI *abi.ABI) ([]string, error) {
+	values, err := cmd.Flags().GetStringArray("event")
+	if err != nil {
+		return nil, err
+	}
+	return parseEventNames(contractABI, values)
+}
+
+// flagUnsignedSender returns the sender of an unsigned transaction, the only kind that can be
+// made without a signer
+func flagUnsignedSender(cmd *cobra.Command, unsignedFile string) (*common.Address, error) {
+	from, err := cmd.Flags().GetString("from")
+	if err != nil {
+		return nil, err
+	}
+	if unsignedFile == "" || from == "" {
+		return nil, errNoSigner
+	}
+	if !common.IsHexAddress(from) {
+		return nil, fmt.Errorf("invalid sender address: %s", from)
+	}
+	r := common.HexToAddress(from)
+	return &r, nil
+}
+
+// inputUnsignedSender asks for the sender when there's no signer, the transaction can then
+// only be saved unsigned
+func inputUnsignedSender() (*common.Address, error) {
+	fmt.Printf("signer not set, the transaction can only be saved unsigned\n")
+	for {
+		from := strings.TrimSpace(inputText("from address: "))
+		switch {
+		case from == "" || from == "..":
+			return nil, errAborted
+		case common.IsHexAddress(from):
+			r := common.HexToAddress(from)
+			return &r, nil
+		}
+		fmt.Printf("invalid address\n")
+	}
+}
+
+func flagTransactParams(cmd *cobra.Command) (*transactParams, error) {
+	r := &transactParams{}
+	f := cmd.Flags()
+	var err error
+	if r.value, err = flagBigInt(cmd, "value"); err != nil {
+		return nil, err
+	}
+	if r.gasPrice, err = flagBigInt(cmd, "gas-price"); err != nil {
+		return nil, err
+	}
+	if r.gasLimit, err = f.GetUint64("gas-limit"); err != nil {
+		return nil, err
+	}
+	if r.dryRun, err = f.GetBool("dry-run"); err != nil {
+		return nil, err
+	}
+	if r.yes, err = f.GetBool("yes"); err != nil {
+		return nil, err
+	}
+	if r.feeCap, err = flagBigInt(cmd, "max-fee"); err != nil {
+		return nil, err
+	}
+	if r.tipCap, err = flagBigInt(cmd, "priority-fee"); err != nil {
+		return nil, err
+	}
+	if s, err := f.GetString("nonce"); err != nil {
+		return nil, err
+	} else if s != "" {
+		nonce, err := strconv.ParseUint(s, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid nonce: %w", err)
+		}
+		r.nonce = &nonce
+	}
+	if r.unsignedFile, err = f.GetString("unsigned"); err != nil {
+		return nil, err
+	}
+	if txSigner == nil {
+		if r.from, err = flagUnsignedSender(cmd, r.unsignedFile); err != nil {
+			return nil, err
+		}
+	}
+	if r.gasPrice == nil && r.feeCap == nil && r.tipCap == nil {
+		r.gasPrice = transactDefaults.gasPrice
+	}
+	if r.gasLimit == 0 {
+		r.gasLimit = transactDefaults.gasLimit
+	}
+	return r, nil
+}
+
+func flagBigInt(cmd *cobra.Command, name string) (*big.Int, error) {
+	v, err := cmd.Flags().GetString(name)
+	if err != nil || v == "" {
+		return nil, err
+	}
+	r, ok := new(big.Int).SetString(v, 10)
+	if !ok {
+		return nil, fmt.Errorf("invalid %s: %s", name, v)
+	}
+	return r, nil
+}
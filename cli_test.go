@@ -0,0 +1,76 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// restoreSettings restores the settings changed by the profile after the test
+func restoreSettings(t *testing.T) {
+	receipt, watch, defaults, output := receiptConfig, watchConfig, transactDefaults, outputFormat
+	t.Cleanup(func() {
+		receiptConfig, watchConfig, transactDefaults, outputFormat = receipt, watch, defaults, output
+	})
+}
+
+func findCommand(t *testing.T, path ...string) *cobra.Command {
+	cmd, _, err := newRootCommand().Find(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cmd
+}
+
+func TestApplyCommandSettings(t *testing.T) {
+	restoreSettings(t)
+	p := &profile{
+		Receipt: &profileReceipt{Wait: true, Confirmations: 2, Timeout: "1m"},
+		Watch:   &profileWatch{Confirmations: 3, PollInterval: "10s"},
+		Output:  outputJSON,
+	}
+	for _, i := range []struct {
+		path    []string
+		args    []string
+		receipt receiptSettings
+		watch   watchSettings
+		output  string
+	}{
+		{
+			[]string{"events", "watch"}, nil,
+			receiptSettings{wait: true, confirmations: 2, timeout: time.Minute},
+			watchSettings{poll: false, pollInterval: 10 * time.Second, confirmations: 3},
+			outputJSON,
+		},
+		{
+			[]string{"events", "watch"}, []string{"--poll", "--poll-interval", "1s", "-o", "text"},
+			receiptSettings{wait: true, confirmations: 2, timeout: time.Minute},
+			watchSettings{poll: true, pollInterval: time.Second, confirmations: 3},
+			outputText,
+		},
+		{
+			[]string{"events", "watch"}, []string{"--confirmations", "7"},
+			receiptSettings{wait: true, confirmations: 2, timeout: time.Minute},
+			watchSettings{poll: false, pollInterval: 10 * time.Second, confirmations: 7},
+			outputJSON,
+		},
+		{
+			[]string{"transact"}, []string{"--confirmations", "5", "--wait=false"},
+			receiptSettings{wait: false, confirmations: 5, timeout: time.Minute},
+			watchSettings{poll: false, pollInterval: 10 * time.Second, confirmations: 3},
+			outputJSON,
+		},
+	} {
+		cmd := findCommand(t, i.path...)
+		if err := cmd.ParseFlags(i.args); err != nil {
+			t.Fatal(err)
+		}
+		if err := applyCommandSettings(cmd, p); err != nil {
+			t.Fatal(err)
+		}
+		if receiptConfig != i.receipt || watchConfig != i.watch || outputFormat != i.output {
+			t.Errorf("%s %v: expecting %+v %+v %s, got %+v %+v %s", cmd.Name(), i.args, i.receipt, i.watch, i.output, receiptConfig, watchConfig, outputFormat)
+		}
+	}
+}
@@ -5,184 +5,760 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
-	"os"
-	"os/signal"
-	"reflect"
+	"math/big"
+	"path/filepath"
 	"strings"
-	"syscall"
+	"time"
 
+	"github.com/c-bata/go-prompt"
+	"github.com/ethereum/go-ethereum"
 	"github.com/ethereum/go-ethereum/accounts/abi"
 	"github.com/ethereum/go-ethereum/accounts/abi/bind"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/types"
-	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v2"
 )
 
-var menuCommands = map[string]func(){
-	"signer/key": cmdConfigSignerKey,
-	// "signer/ledger": cmdConfigSignerLedger,
+func cmdConfigSignerKey(cmd *cobra.Command, args []string) error {
+	key, keyFile, err := inputKeyFile()
+	if err != nil {
+		return fmt.Errorf("can't read key file: %w", err)
+	}
+	s := newKeySigner(key)
+	s.keyFile = keyFile
+	setSigner(s)
+	return nil
 }
 
-func cmdConfigSignerKey() {
-	key, err := inputKeyFile()
+func cmdConfigSignerKeystore(cmd *cobra.Command, args []string) error {
+	def := defaultKeystoreDir()
+	if s, ok := txSigner.(*keystoreSigner); ok {
+		def = s.dir
+	}
+	p, err := filepath.Abs(".")
+	if err != nil {
+		return err
+	}
+	dir, err := inputFilename(fmt.Sprintf("keystore directory (%s): ", def), p, false)
 	if err != nil {
-		fmt.Printf("can't read key file: %s\n", err)
-		return
+		return err
 	}
-	txSigner = newKeySigner(key)
+	if dir == "" {
+		dir = def
+	}
+	ks, err := openKeystore(dir)
+	if err != nil {
+		return err
+	}
+	choices := keystoreAccountSuggestions(ks)
+	showSuggestions(choices)
+	address, ok := inputMultiChoice("account (%s): ", choices[0].Text, choices, showSuggestions)
+	if !ok {
+		return errAborted
+	}
+	account, err := findKeystoreAccount(ks, address)
+	if err != nil {
+		return err
+	}
+	timeout, ok := inputDurationWithDefault("unlock timeout, 0 to keep it unlocked (%s): ", 0)
+	if !ok {
+		return errAborted
+	}
+	s := newKeystoreSigner(ks, dir, account)
+	s.unlockTimeout = timeout
+	if err = s.unlock(); err != nil {
+		return err
+	}
+	setSigner(s)
+	return nil
 }
 
-func cmdConfigSignerLedger() {}
+func cmdConfigSignerMnemonic(cmd *cobra.Command, args []string) error {
+	mnemonic, err := inputSecret("mnemonic: ")
+	if err != nil {
+		return err
+	}
+	fmt.Println()
+	passphrase, err := inputSecret("passphrase (none): ")
+	if err != nil {
+		return err
+	}
+	fmt.Println()
+	path, ok := inputTextWithDefault("derivation path (%s): ", defaultHDPath)
+	if !ok {
+		return errAborted
+	}
+	count, ok := inputIntWithDefault("accounts to list (%d): ", 10)
+	if !ok {
+		return errAborted
+	}
+	w, err := newHDWallet(mnemonic, passphrase, path)
+	if err != nil {
+		return err
+	}
+	w.count = count
+	s, err := chooseHDAccount(w, 0)
+	if err != nil {
+		return err
+	}
+	setSigner(s)
+	return nil
+}
 
-var (
-	errNotConstant = errors.New("method is not constant")
-	errConstant    = errors.New("method is constant")
-	errAborted     = errors.New("aborted")
-)
+func cmdConfigSignerAccount(cmd *cobra.Command, args []string) error {
+	current, ok := txSigner.(*hdSigner)
+	if !ok {
+		return errNotHDWallet
+	}
+	s, err := chooseHDAccount(current.wallet, current.index)
+	if err != nil {
+		return err
+	}
+	setSigner(s)
+	return nil
+}
 
-func executeConstantMethod(cl *ethclient.Client, addr *common.Address, abi *abi.ABI, name string) ([]interface{}, error) {
-	fmt.Printf("constant call arguments:\n")
-	args, err := inputArguments(abi.Methods[name].Inputs, false)
+func cmdConfigSignerExternal(cmd *cobra.Command, args []string) error {
+	def := defaultExternalSigner()
+	if s, ok := txSigner.(*externalSigner); ok {
+		def = s.endpoint
+	}
+	endpoint, ok := inputTextWithDefault("signer ipc path or url (%s): ", def)
+	if !ok {
+		return errAborted
+	}
+	ext, accs, err := openExternalSigner(endpoint)
 	if err != nil {
-		return nil, err
+		return err
 	}
-	bc := bind.NewBoundContract(*addr, *abi, cl, cl, cl)
-	method := abi.Methods[name]
-	if !method.IsConstant() {
-		return nil, errNotConstant
+	choices := externalAccountSuggestions(accs)
+	showSuggestions(choices)
+	addr, ok := inputMultiChoice("account (%s): ", choices[0].Text, choices, showSuggestions)
+	if !ok {
+		ext.Close()
+		return errAborted
 	}
-	// call method
-	res := newCallResult(method.Outputs)
-	if err := bc.Call(nil, res.res, name, args...); err != nil {
-		return nil, err
+	for _, i := range accs {
+		if i.Address.Hex() == addr {
+			setSigner(&externalSigner{signer: ext, endpoint: endpoint, account: i})
+			return nil
+		}
+	}
+	ext.Close()
+	return errUnknownAccount
+}
+
+func cmdConfigSignerLedger(cmd *cobra.Command, args []string) error { return errNotImplemented }
+
+func cmdChainMine(cmd *cobra.Command, args []string) error {
+	n, ok := inputIntWithDefault("blocks (%d): ", 1)
+	if !ok {
+		return errAborted
+	}
+	for i := 0; i < n; i++ {
+		sess.sim.Commit()
+	}
+	fmt.Printf("block number: %s\n", sess.sim.Blockchain().CurrentBlock().Number())
+	return nil
+}
+
+func cmdChainTime(cmd *cobra.Command, args []string) error {
+	d, ok := inputDurationWithDefault("time to move forward (%s): ", time.Hour)
+	if !ok {
+		return errAborted
+	}
+	if err := sess.sim.AdjustTime(d); err != nil {
+		return err
 	}
-	return res.results(), nil
+	sess.sim.Commit()
+	fmt.Printf("block time: %s\n", time.Unix(int64(sess.sim.Blockchain().CurrentBlock().Time()), 0))
+	return nil
 }
 
-type callResult struct {
-	mo  abi.Arguments
-	res interface{}
+func cmdChainAutoCommit(cmd *cobra.Command, args []string) error {
+	autoCommit, ok := inputYesNo("mine transactions immediately? (%s): ", sess.sim.autoCommit)
+	if !ok {
+		return errAborted
+	}
+	sess.sim.autoCommit = autoCommit
+	return nil
 }
 
-func newCallResult(mo abi.Arguments) *callResult {
-	switch len(mo) {
-	case 0:
+func cmdChainAccounts(cmd *cobra.Command, args []string) error {
+	choices := make([]prompt.Suggest, 0, len(sess.sim.keys))
+	var current string
+	for _, i := range sess.sim.keys {
+		addr := crypto.PubkeyToAddress(i.PublicKey)
+		balance, err := sess.sim.BalanceAt(context.Background(), addr, nil)
+		if err != nil {
+			return err
+		}
+		choices = append(choices, prompt.Suggest{Text: addr.Hex(), Description: formatEther(balance) + " ether"})
+		if txSigner != nil && txSigner.address() == addr {
+			current = addr.Hex()
+		}
+	}
+	showSuggestions(choices)
+	addr, ok := inputMultiChoice("sign with account (%s): ", current, choices, showSuggestions)
+	if !ok {
+		return errAborted
+	}
+	for n, i := range choices {
+		if i.Text == addr {
+			setSigner(newKeySigner(sess.sim.keys[n]))
+		}
+	}
+	return nil
+}
+
+func cmdSettingsOutput(cmd *cobra.Command, args []string) error {
+	f, ok := inputMultiChoiceString("output format (%s): ", outputFormat, outputFormats, func(c []prompt.Suggest) {
+		fmt.Printf("\nchoose the output format: %s\n", strings.Join(outputFormats, ", "))
+	})
+	if !ok {
+		return errAborted
+	}
+	outputFormat = f
+	return nil
+}
+
+func cmdSettingsReceipt(cmd *cobra.Command, args []string) error {
+	wait, ok := inputYesNo("wait for transaction receipts? (%s): ", receiptConfig.wait)
+	if !ok {
+		return errAborted
+	}
+	if !wait {
+		receiptConfig.wait = false
 		return nil
-	case 1:
-		return &callResult{mo: mo, res: reflect.New(mo[0].Type.GetType()).Interface()}
 	}
-	r := make([]interface{}, 0, len(mo))
-	for _, i := range mo {
-		r = append(r, reflect.New(i.Type.GetType()).Interface())
+	confirmations, ok := inputIntWithDefault("confirmations (%d): ", receiptConfig.confirmations)
+	if !ok {
+		return errAborted
+	}
+	if confirmations < 1 {
+		return errors.New("confirmations must be at least 1")
 	}
-	return &callResult{mo: mo, res: &r}
+	timeout, ok := inputDurationWithDefault("timeout (%s, 0 to wait forever): ", receiptConfig.timeout)
+	if !ok {
+		return errAborted
+	}
+	receiptConfig = receiptSettings{wait: true, confirmations: confirmations, timeout: timeout}
+	return nil
+}
+
+func cmdSettingsWatch(cmd *cobra.Command, args []string) error {
+	confirmations, ok := inputIntWithDefault("confirmations (%d): ", watchConfig.confirmations)
+	if !ok {
+		return errAborted
+	}
+	if confirmations < 1 {
+		return errInvalidWatchConfirmations
+	}
+	poll, ok := inputYesNo("poll even when subscriptions are available? (%s): ", watchConfig.poll)
+	if !ok {
+		return errAborted
+	}
+	interval, ok := inputDurationWithDefault("poll interval (%s): ", watchConfig.pollInterval)
+	if !ok {
+		return errAborted
+	}
+	if interval <= 0 {
+		return errInvalidPollInterval
+	}
+	watchConfig = watchSettings{poll: poll, pollInterval: interval, confirmations: confirmations}
+	return nil
 }
 
-func indirectInterface(v interface{}) interface{} {
-	return reflect.Indirect(reflect.ValueOf(v)).Interface()
+func cmdContractsList(cmd *cobra.Command, args []string) error {
+	type contractOutput struct {
+		Name     string `json:"name"`
+		Address  string `json:"address,omitempty"`
+		Selected bool   `json:"selected"`
+	}
+	r := make([]contractOutput, 0, len(sess.contracts))
+	for _, i := range sess.contracts {
+		c := contractOutput{Name: i.name, Selected: i == sess.sessionContract}
+		if i.address != (common.Address{}) {
+			c.Address = i.address.Hex()
+		}
+		r = append(r, c)
+	}
+	if outputFormat != outputText {
+		printJSON(r)
+		return nil
+	}
+	for _, i := range r {
+		mark := " "
+		if i.Selected {
+			mark = "*"
+		}
+		addr := i.Address
+		if addr == "" {
+			addr = "(not deployed)"
+		}
+		fmt.Printf("%s %s %s\n", mark, i.Name, addr)
+	}
+	return nil
 }
 
-func (cr *callResult) results() []interface{} {
-	if s, ok := cr.res.(*[]interface{}); ok {
-		r := make([]interface{}, 0, 4)
-		for _, i := range *s {
-			r = append(r, indirectInterface(i))
+func cmdContractsAdd(cmd *cobra.Command, args []string) error {
+	p, err := filepath.Abs(".")
+	if err != nil {
+		return err
+	}
+	abiFile, err := inputFilename("abi file: ", p, true)
+	if err != nil {
+		return err
+	}
+	artifact, err := loadArtifact(abiFile, "", true)
+	if err != nil {
+		return fmt.Errorf("can't read abi: %w", err)
+	}
+	c := &sessionContract{abi: artifact.abi, bytecode: artifact.bytecode, unlinked: artifact.unlinked, abiFile: abiFile, artifact: artifact.name}
+	for {
+		c.name = strings.TrimSpace(inputText(fmt.Sprintf("name (%s): ", artifact.name)))
+		if c.name == ".." {
+			return errAborted
+		}
+		if c.name == "" {
+			c.name = artifact.name
+		}
+		if strings.ContainsAny(c.name, " \t"+nameSep) {
+			fmt.Printf("invalid name: can't contain spaces or %s\n", nameSep)
+		} else if sess.findContract(c.name) != nil {
+			fmt.Printf("invalid name: %s\n", errContractExists)
+		} else {
+			break
+		}
+	}
+	var def string
+	if addr, ok := artifact.networkAddress(sess.chainID); ok {
+		def = addr.Hex()
+	}
+	for {
+		addr := strings.TrimSpace(inputText(fmt.Sprintf("address (%s): ", def)))
+		if addr == ".." {
+			return errAborted
+		}
+		if addr == "" {
+			addr = def
 		}
-		return r
+		if addr == "" || common.IsHexAddress(addr) {
+			c.address = common.HexToAddress(addr)
+			break
+		}
+		fmt.Printf("invalid address: %s\n", addr)
+	}
+	if err = sess.addContract(c); err != nil {
+		return err
 	}
-	return []interface{}{indirectInterface(cr.res)}
+	rebuildMenu = true
+	return sess.selectContract(c.name)
 }
 
-func executeTransactMethod(cl *ethclient.Client, addr *common.Address, abi *abi.ABI, name string) (*types.Transaction, error) {
-	method := abi.Methods[name]
-	if method.IsConstant() {
-		return nil, errConstant
+func cmdContractsRemove(cmd *cobra.Command, args []string) error {
+	if err := sess.removeContract(args[0]); err != nil {
+		return err
+	}
+	rebuildMenu = true
+	return nil
+}
+
+func cmdContractsSelect(cmd *cobra.Command, args []string) error {
+	if err := sess.selectContract(args[0]); err != nil {
+		return err
+	}
+	rebuildMenu = true
+	return nil
+}
+
+func cmdConfigShow(cmd *cobra.Command, args []string) error {
+	cfg, err := readConfig(rootFlags.configFile)
+	if err != nil {
+		return err
 	}
-	fmt.Printf("transaction arguments:\n")
-	args, err := inputArguments(abi.Methods[name].Inputs, false)
+	b, err := yaml.Marshal(sessionProfile())
 	if err != nil {
-		return nil, err
+		return err
+	}
+	fmt.Printf("config file: %s\n", rootFlags.configFile)
+	fmt.Printf("profiles: %s\n", strings.Join(cfg.profileNames(), ", "))
+	if profileName != "" {
+		fmt.Printf("session (profile %s):\n", profileName)
+	} else {
+		fmt.Printf("session:\n")
 	}
+	fmt.Printf("%s", b)
+	return nil
+}
+
+func cmdConfigSave(cmd *cobra.Command, args []string) error {
+	cfg, err := readConfig(rootFlags.configFile)
+	if err != nil {
+		return err
+	}
+	var name string
+	for name == "" {
+		var ok bool
+		if name, ok = inputTextWithDefault("profile name (%s): ", profileName); !ok {
+			return errAborted
+		}
+		if strings.ContainsAny(name, " \t"+nameSep) {
+			fmt.Printf("invalid name: can't contain spaces or %s\n", nameSep)
+			name = ""
+		}
+	}
+	if _, ok := cfg.Profiles[name]; ok && name != profileName {
+		overwrite, ok := inputYesNo("profile exists. overwrite? (%s): ", false)
+		if !ok || !overwrite {
+			return errAborted
+		}
+	}
+	cfg.Profiles[name] = sessionProfile()
+	if err = cfg.write(rootFlags.configFile); err != nil {
+		return fmt.Errorf("can't save config: %w", err)
+	}
+	profileName = name
+	rebuildMenu = true
+	fmt.Printf("profile %s saved to %s\n", name, rootFlags.configFile)
+	return nil
+}
+
+func cmdConfigLoad(cmd *cobra.Command, args []string) error {
+	cfg, err := readConfig(rootFlags.configFile)
+	if err != nil {
+		return err
+	}
+	p, err := cfg.profile(args[0])
+	if err != nil {
+		return err
+	}
+	// the current session is kept if the profile can't be opened
+	oldSess, oldSigner := sess, txSigner
+	sess, txSigner = &session{close: func() {}}, nil
+	if err = startSession(p); err == nil {
+		err = sess.deploySimulated("", nil)
+	}
+	if err != nil {
+		sess.close()
+		sess, txSigner = oldSess, oldSigner
+		return err
+	}
+	oldSess.close()
+	if oldSigner != nil && oldSigner != txSigner {
+		oldSigner.close()
+	}
+	if err = applyProfileSettings(p); err != nil {
+		return err
+	}
+	profileName = args[0]
+	updateChainCommand(cmd.Root())
+	rebuildMenu = true
+	return nil
+}
+
+func cmdConfigEdit(cmd *cobra.Command, args []string) error {
+	cfg, err := readConfig(rootFlags.configFile)
+	if err != nil {
+		return err
+	}
+	p, err := cfg.profile(args[0])
+	if err != nil {
+		return err
+	}
+	var ok bool
+	if !p.Simulated {
+		if p.URL, ok = inputTextWithDefault("client url (%s): ", p.URL); !ok {
+			return errAborted
+		}
+		chainID, ok := inputIntWithDefault("chain id (%d): ", int(p.ChainID))
+		if !ok {
+			return errAborted
+		}
+		p.ChainID = uint64(chainID)
+	}
+	if p.GasPrice, ok = inputTextWithDefault("gas price (%s, \"\" to estimate): ", p.GasPrice); !ok {
+		return errAborted
+	}
+	if p.GasPrice == `""` {
+		p.GasPrice = ""
+	} else if _, valid := new(big.Int).SetString(p.GasPrice, 10); p.GasPrice != "" && !valid {
+		return fmt.Errorf("invalid gas price: %s", p.GasPrice)
+	}
+	gasLimit, ok := inputIntWithDefault("gas limit (%d, 0 to estimate): ", int(p.GasLimit))
+	if !ok {
+		return errAborted
+	}
+	p.GasLimit = uint64(gasLimit)
+	if p.Receipt == nil {
+		p.Receipt = &profileReceipt{
+			Wait:          receiptConfig.wait,
+			Confirmations: receiptConfig.confirmations,
+			Timeout:       receiptConfig.timeout.String(),
+		}
+	}
+	if p.Receipt.Confirmations, ok = inputIntWithDefault("confirmations (%d): ", p.Receipt.Confirmations); !ok {
+		return errAborted
+	}
+	if p.Receipt.Confirmations < 1 {
+		return errors.New("confirmations must be at least 1")
+	}
+	timeout, err := time.ParseDuration(p.Receipt.Timeout)
+	if err != nil {
+		timeout = receiptConfig.timeout
+	}
+	if timeout, ok = inputDurationWithDefault("receipt timeout (%s, 0 to wait forever): ", timeout); !ok {
+		return errAborted
+	}
+	p.Receipt.Timeout = timeout.String()
+	if p.Output == "" {
+		p.Output = outputText
+	}
+	if p.Output, ok = inputMultiChoiceString("output format (%s): ", p.Output, outputFormats, func(c []prompt.Suggest) {
+		fmt.Printf("\nchoose the output format: %s\n", strings.Join(outputFormats, ", "))
+	}); !ok {
+		return errAborted
+	}
+	if err = cfg.write(rootFlags.configFile); err != nil {
+		return fmt.Errorf("can't save config: %w", err)
+	}
+	fmt.Printf("profile %s saved to %s\n", args[0], rootFlags.configFile)
+	return nil
+}
+
+var (
+	errNotConstant = errors.New("method is not constant")
+	errConstant    = errors.New("method is constant")
+	errNotPayable  = errors.New("method is not payable")
+	errAborted     = errors.New("aborted")
+)
+
+func executeConstantMethod(cl backend, addr *common.Address, abi *abi.ABI, name string, args []interface{}) ([]interface{}, error) {
 	bc := bind.NewBoundContract(*addr, *abi, cl, cl, cl)
-	opts := bind.NewKeyedTransactor(txSigner.key)
-	if abi.Methods[name].IsPayable() {
+	method := abi.Methods[name]
+	if !method.IsConstant() {
+		return nil, errNotConstant
+	}
+	// call method
+	var res []interface{}
+	if err := bc.Call(&bind.CallOpts{}, &res, name, args...); err != nil {
+		return nil, explainCallError(abi, err)
+	}
+	return res, nil
+}
+
+type transactParams struct {
+	value    *big.Int
+	gasPrice *big.Int
+	// dynamic fees, suggested when not set
+	feeCap   *big.Int
+	tipCap   *big.Int
+	gasLimit uint64
+	// only show the transaction preview
+	dryRun bool
+	// send without asking for confirmation
+	yes bool
+	// nonce given by hand, the pending nonce when nil
+	nonce *uint64
+	// save the transaction unsigned to the file instead of sending it. from is the sender
+	// when there's no signer
+	unsignedFile string
+	from         *common.Address
+}
+
+// transactDefaults holds the gas price and limit used instead of estimating them
+var transactDefaults transactParams
+
+func inputTransactParams(cl backend, method *abi.Method) (*transactParams, error) {
+	r := &transactParams{}
+	if method.IsPayable() {
 		send, ok := inputYesNo("method is payable. send amount with transaction? (%s): ", false)
 		if !ok {
 			return nil, errAborted
 		}
 		if send {
-			amount := inputBigInt("amount: ")
-			opts.Value = amount
+			r.value = inputBigInt("amount: ")
 		}
 	}
-	if estimateGasPrice, ok := inputYesNo("estimate gas price? (%s): ", true); !ok {
+	fees, err := suggestFees(cl)
+	if err != nil {
+		return nil, fmt.Errorf("can't suggest fees: %w", err)
+	}
+	if fees.london() {
+		fmt.Printf("base fee: %s gwei\n", formatGwei(fees.baseFee))
+		if estimateFees, ok := inputYesNo("estimate fees? (%s): ", true); !ok {
+			return nil, errAborted
+		} else if !estimateFees {
+			r.tipCap = inputBigIntWithDefault("max priority fee (%s): ", fees.tipCap)
+			r.feeCap = inputBigIntWithDefault("max fee (%s): ", maxFee(fees.baseFee, r.tipCap))
+		}
+	} else if estimateGasPrice, ok := inputYesNo("estimate gas price? (%s): ", transactDefaults.gasPrice == nil); !ok {
 		return nil, errAborted
 	} else if !estimateGasPrice {
-		sugg, err := cl.SuggestGasPrice(context.Background())
-		if err != nil {
-			return nil, err
+		def := transactDefaults.gasPrice
+		if def == nil {
+			def = fees.gasPrice
 		}
-		opts.GasPrice = inputBigIntWithDefault("gas price (%s): ", sugg)
+		r.gasPrice = inputBigIntWithDefault("gas price (%s): ", def)
 	}
-	if estimateGasLimit, ok := inputYesNo("estimate gas limit? (%s): ", true); !ok {
+	if estimateGasLimit, ok := inputYesNo("estimate gas limit? (%s): ", transactDefaults.gasLimit == 0); !ok {
 		return nil, errAborted
 	} else if !estimateGasLimit {
-		if gl, ok := inputIntWithDefault("gas limit (%d): ", 0); ok {
-			opts.GasLimit = uint64(gl)
+		if gl, ok := inputIntWithDefault("gas limit (%d): ", int(transactDefaults.gasLimit)); ok {
+			r.gasLimit = uint64(gl)
 		}
 	}
-	return bc.Transact(opts, name, args...)
+	return r, nil
 }
 
-func listEvents(cl *ethclient.Client, addr *common.Address, abi *abi.ABI, name string) {
-	filters, err := inputFilters(abi.Events[name].Inputs)
+func executeTransactMethod(cl backend, addr *common.Address, abi *abi.ABI, name string, args []interface{}, params *transactParams) (*types.Transaction, error) {
+	method := abi.Methods[name]
+	if method.IsConstant() {
+		return nil, errConstant
+	}
+	if params.value != nil && params.value.Sign() != 0 && !method.IsPayable() {
+		return nil, errNotPayable
+	}
+	input, err := abi.Pack(name, args...)
 	if err != nil {
-		fmt.Printf("error parsing filter fields: %s\n", err)
-		return
+		return nil, err
 	}
-	opts := &bind.FilterOpts{}
-	startBlock, ok := inputIntWithDefault("start block (%d): ", 0)
-	if !ok {
-		fmt.Printf("aborted\n")
-		return
+	opts, err := transactorFor(cl, params)
+	if err != nil {
+		return nil, err
+	}
+	req := &txRequest{contract: sess.name, to: addr, method: &method, args: args, data: input}
+	if err = prepareTransaction(cl, abi, opts, req, params, true); err != nil {
+		return nil, err
 	}
-	opts.Start = uint64(startBlock)
-	if lastBlock, ok := inputIntWithDefault("end block (last, %d): ", -1); !ok {
-		fmt.Printf("aborted\n")
-		return
-	} else if lastBlock >= 0 {
-		lb := uint64(lastBlock)
-		opts.End = &lb
+	if params.unsignedFile != "" {
+		return nil, writeUnsignedTransaction(cl, opts, req, params.unsignedFile)
 	}
 	bc := bind.NewBoundContract(*addr, *abi, cl, cl, cl)
-	logs, sub, err := bc.FilterLogs(opts, name, filters...)
+	tx, err := bc.Transact(opts, name, args...)
 	if err != nil {
-		fmt.Printf("error listing logs: %s\n", err)
-		return
+		msg := ethereum.CallMsg{From: opts.From, To: addr, Value: opts.Value, Data: input}
+		return nil, explainTransactError(cl, abi, msg, err)
 	}
-	defer close(logs)
-	defer sub.Unsubscribe()
-	for {
-		if err := <-sub.Err(); err != nil {
-			fmt.Printf("error listing logs: %s\n", err)
-			return
+	return tx, nil
+}
+
+// transactorFor returns the transactor of the signer, or of the sender of an unsigned transaction
+func transactorFor(cl backend, params *transactParams) (*bind.TransactOpts, error) {
+	if params.from != nil {
+		return newUnsignedTransactor(cl, *params.from)
+	}
+	return newTransactor(cl)
+}
+
+func newTransactor(cl backend) (*bind.TransactOpts, error) {
+	chainID, err := cl.ChainID(context.Background())
+	if err != nil {
+		return nil, err
+	}
+	if err = sess.checkChainID(chainID); err != nil {
+		return nil, err
+	}
+	if txSigner == nil {
+		return nil, errNoSigner
+	}
+	return txSigner.transactor(chainID)
+}
+
+// deployContract sends the contract creation. without params the transaction is sent with the
+// estimated fees and no confirmation
+func deployContract(cl backend, contractABI *abi.ABI, bytecode []byte, args []interface{}, params *transactParams) (common.Address, *types.Transaction, error) {
+	confirm := params != nil
+	if params == nil {
+		params = &transactParams{}
+	}
+	if params.value != nil && params.value.Sign() != 0 && !contractABI.Constructor.IsPayable() {
+		return common.Address{}, nil, errNotPayable
+	}
+	input, err := contractABI.Pack("", args...)
+	if err != nil {
+		return common.Address{}, nil, err
+	}
+	data := append(append([]byte{}, bytecode...), input...)
+	opts, err := transactorFor(cl, params)
+	if err != nil {
+		return common.Address{}, nil, err
+	}
+	req := &txRequest{contract: sess.name, method: &contractABI.Constructor, args: args, data: data}
+	if err = prepareTransaction(cl, contractABI, opts, req, params, confirm); err != nil {
+		return common.Address{}, nil, err
+	}
+	if params.unsignedFile != "" {
+		return common.Address{}, nil, writeUnsignedTransaction(cl, opts, req, params.unsignedFile)
+	}
+	addr, tx, _, err := bind.DeployContract(opts, *contractABI, bytecode, cl, args...)
+	if err != nil {
+		msg := ethereum.CallMsg{From: opts.From, Value: opts.Value, Data: data}
+		return common.Address{}, nil, explainTransactError(cl, contractABI, msg, err)
+	}
+	return addr, tx, nil
+}
+
+func eventQuery(addr *common.Address, ev *abi.Event, filters [][]interface{}) (ethereum.FilterQuery, error) {
+	topics, err := abi.MakeTopics(append([][]interface{}{{ev.ID}}, filters...)...)
+	if err != nil {
+		return ethereum.FilterQuery{}, fmt.Errorf("invalid filter: %w", err)
+	}
+	return ethereum.FilterQuery{Addresses: []common.Address{*addr}, Topics: topics}, nil
+}
+
+// allEvents is the argument selecting several events
+const allEvents = "all"
+
+// contractEventsQuery queries the logs of the named events, or every log of the contract
+// without names
+func contractEventsQuery(addr *common.Address, contractABI *abi.ABI, names []string) ethereum.FilterQuery {
+	r := ethereum.FilterQuery{Addresses: []common.Address{*addr}}
+	if len(names) > 0 {
+		ids := make([]common.Hash, 0, len(names))
+		for _, i := range names {
+			ids = append(ids, contractABI.Events[i].ID)
 		}
-		select {
-		case l := <-logs:
-			eventData := make(map[string]interface{}, 8)
-			if err := bc.UnpackLogIntoMap(eventData, name, l); err != nil {
-				fmt.Printf("error listing logs: %s\n", err)
-				return
-			}
-			fmt.Print(formatEvent(abi.Events[name].Inputs, eventData, l.BlockNumber))
-		default:
-			return
+		r.Topics = [][]common.Hash{ids}
+	}
+	return r
+}
+
+// logPrinter matches the logs to the abi events by their first topic. logs without a matching
+// event are printed raw
+func logPrinter(contractABI *abi.ABI, p *eventPrinter) func(l types.Log, status string) error {
+	return func(l types.Log, status string) error {
+		ev, eventData, err := decodeLog(contractABI, &l)
+		if err != nil {
+			p.printRaw(&l, status)
+			return nil
 		}
+		p.printStatus(ev, eventData, &l, status)
+		return nil
 	}
+}
 
+// listEvents prints the logs of the query in the block range. names adds the event names to the
+// text output
+func listEvents(cl backend, contractABI *abi.ABI, query ethereum.FilterQuery, names bool, start, last uint64) error {
+	p := &eventPrinter{list: true, names: names}
+	defer p.close()
+	printLog := logPrinter(contractABI, p)
+	ctx, cancel := interruptContext()
+	defer cancel()
+	next, err := scanLogs(ctx, cl, query, start, last, func(l types.Log) error { return printLog(l, "") })
+	if ctx.Err() != nil {
+		infof("interrupted, blocks %d-%d not listed\n", next, last)
+		return errAborted
+	} else if err != nil {
+		return fmt.Errorf("error listing logs (blocks %d-%d not listed): %w", next, last, err)
+	}
+	return nil
 }
 
-func formatEvent(inputs abi.Arguments, eventData map[string]interface{}, blockNumber uint64) string {
+func formatEventValues(inputs abi.Arguments, eventData map[string]interface{}) string {
 	var values []string
 	for _, i := range inputs {
 		b, err := json.Marshal(eventData[i.Name])
@@ -192,41 +768,5 @@ func formatEvent(inputs abi.Arguments, eventData map[string]interface{}, blockNu
 		}
 		values = append(values, fmt.Sprintf("%s=%s", i.Name, string(b)))
 	}
-	return fmt.Sprintf("  block %d: %s\n", blockNumber, strings.Join(values, " "))
-}
-
-func watchEvents(cl *ethclient.Client, addr *common.Address, abi *abi.ABI, name string) {
-	filters, err := inputFilters(abi.Events[name].Inputs)
-	if err != nil {
-		fmt.Printf("error parsing filter fields: %s\n", err)
-		return
-	}
-	bc := bind.NewBoundContract(*addr, *abi, cl, cl, cl)
-	logs, sub, err := bc.WatchLogs(nil, name, filters...)
-	if err != nil {
-		fmt.Printf("error watching logs: %s\n", err)
-		return
-	}
-	defer close(logs)
-	defer sub.Unsubscribe()
-	sig := make(chan os.Signal, 1)
-	signal.Notify(sig, syscall.SIGTERM, syscall.SIGINT)
-	for {
-		select {
-		case <-sig:
-			return
-		case err := <-sub.Err():
-			if err != nil {
-				fmt.Printf("error watching logs: %s\n", err)
-				return
-			}
-		case l := <-logs:
-			eventData := make(map[string]interface{}, 8)
-			if err := bc.UnpackLogIntoMap(eventData, name, l); err != nil {
-				fmt.Printf("error watching logs: %s\n", err)
-				return
-			}
-			fmt.Print(formatEvent(abi.Events[name].Inputs, eventData, l.BlockNumber))
-		}
-	}
+	return strings.Join(values, " ")
 }
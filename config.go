@@ -0,0 +1,289 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"gopkg.in/yaml.v2"
+)
+
+var errUnknownProfile = errors.New("unknown profile")
+
+// config is the configuration file, holding the connection profiles
+type config struct {
+	Profiles map[string]*profile `yaml:"profiles"`
+}
+
+// profile holds everything needed to start a session
+type profile struct {
+	URL       string            `yaml:"url,omitempty"`
+	Simulated bool              `yaml:"simulated,omitempty"`
+	ChainID   uint64            `yaml:"chain_id,omitempty"`
+	Contracts []profileContract `yaml:"contracts,omitempty"`
+	Selected  string            `yaml:"selected,omitempty"`
+	Signer    *profileSigner    `yaml:"signer,omitempty"`
+	GasPrice  string            `yaml:"gas_price,omitempty"`
+	GasLimit  uint64            `yaml:"gas_limit,omitempty"`
+	Receipt   *profileReceipt   `yaml:"receipt,omitempty"`
+	Watch     *profileWatch     `yaml:"watch,omitempty"`
+	Output    string            `yaml:"output,omitempty"`
+}
+
+type profileContract struct {
+	Name string `yaml:"name"`
+	ABI  string `yaml:"abi"`
+	// the contract to use when the abi file has several
+	Contract string `yaml:"contract,omitempty"`
+	Address  string `yaml:"address,omitempty"`
+	// bytecode deployed on simulated chains instead of the bytecode in the artifact
+	Bytecode string `yaml:"bytecode,omitempty"`
+}
+
+type profileSigner struct {
+	Key string `yaml:"key,omitempty"`
+	// keystore directory, the account is also the account of the external signer
+	Keystore string `yaml:"keystore,omitempty"`
+	Account  string `yaml:"account,omitempty"`
+	// mnemonic file and derived account. the password file holds the passphrase
+	Mnemonic string `yaml:"mnemonic,omitempty"`
+	HDPath   string `yaml:"hd_path,omitempty"`
+	Index    uint32 `yaml:"index,omitempty"`
+	// clef ipc path or url, the account is the keystore account field
+	External     string `yaml:"external,omitempty"`
+	PasswordFile string `yaml:"password_file,omitempty"`
+}
+
+type profileReceipt struct {
+	Wait          bool   `yaml:"wait"`
+	Confirmations int    `yaml:"confirmations"`
+	Timeout       string `yaml:"timeout"`
+}
+
+type profileWatch struct {
+	Confirmations int    `yaml:"confirmations"`
+	Poll          bool   `yaml:"poll,omitempty"`
+	PollInterval  string `yaml:"poll_interval"`
+}
+
+// profileName is the name of the profile the session was started from
+var profileName string
+
+// defaultConfigFile returns the config file under $XDG_CONFIG_HOME
+func defaultConfigFile() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "scui.yaml"
+	}
+	return filepath.Join(dir, "scui", "config.yaml")
+}
+
+// readConfig reads the config file. a missing file is an empty config
+func readConfig(fn string) (*config, error) {
+	r := &config{Profiles: map[string]*profile{}}
+	b, err := ioutil.ReadFile(fn)
+	if os.IsNotExist(err) {
+		return r, nil
+	} else if err != nil {
+		return nil, err
+	}
+	if err = yaml.UnmarshalStrict(b, r); err != nil {
+		return nil, fmt.Errorf("%s: %w", fn, err)
+	}
+	if r.Profiles == nil {
+		r.Profiles = map[string]*profile{}
+	}
+	return r, nil
+}
+
+func (c *config) write(fn string) error {
+	b, err := yaml.Marshal(c)
+	if err != nil {
+		return err
+	}
+	if err = os.MkdirAll(filepath.Dir(fn), 0700); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(fn, b, 0600)
+}
+
+func (c *config) profile(name string) (*profile, error) {
+	p, ok := c.Profiles[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", errUnknownProfile, name)
+	}
+	return p, nil
+}
+
+func (c *config) profileNames() []string {
+	r := make([]string, 0, len(c.Profiles))
+	for name := range c.Profiles {
+		r = append(r, name)
+	}
+	sort.Strings(r)
+	return r
+}
+
+// startSession opens the session described by the profile
+func startSession(p *profile) error {
+	if p.Simulated {
+		openSimulatedSession()
+	} else if err := openSession(p.URL); err != nil {
+		return err
+	}
+	if p.ChainID != 0 {
+		sess.pinnedChainID = new(big.Int).SetUint64(p.ChainID)
+		if err := sess.checkChainID(sess.chainID); err != nil {
+			infof("%s, transactions will be refused\n", err)
+		}
+	}
+	if len(p.Contracts) == 0 {
+		return errMissingABI
+	}
+	for _, i := range p.Contracts {
+		c, err := sess.openContract(i.Name, i.ABI, i.Contract, i.Address)
+		if err != nil {
+			return err
+		}
+		if i.Bytecode != "" {
+			if c.bytecode, err = readBytecode(i.Bytecode, c.artifact); err != nil {
+				return fmt.Errorf("can't read bytecode: %w", err)
+			}
+			if c.bytecodeFile, err = filepath.Abs(i.Bytecode); err != nil {
+				return err
+			}
+		}
+		if p.Selected == c.name || p.Selected == "" && sess.sessionContract == nil {
+			sess.sessionContract = c
+		}
+	}
+	if sess.sessionContract == nil {
+		return fmt.Errorf("%w: %s", errUnknownContract, p.Selected)
+	}
+	if p.Signer != nil {
+		s, err := loadProfileSigner(p.Signer)
+		if err != nil {
+			return err
+		}
+		txSigner = s
+	}
+	return nil
+}
+
+// loadProfileSigner opens the key file, keystore account, mnemonic account or external signer
+func loadProfileSigner(p *profileSigner) (signer, error) {
+	switch {
+	case p.Key != "":
+		s, err := loadKeySigner(p.Key, p.PasswordFile)
+		if err != nil {
+			return nil, fmt.Errorf("can't read key file: %w", err)
+		}
+		return s, nil
+	case p.Keystore != "":
+		s, err := loadKeystoreSigner(p.Keystore, p.Account, p.PasswordFile)
+		if err != nil {
+			return nil, fmt.Errorf("can't open keystore: %w", err)
+		}
+		return s, nil
+	case p.Mnemonic != "":
+		w, err := readHDWallet(p.Mnemonic, p.PasswordFile, p.HDPath)
+		if err != nil {
+			return nil, fmt.Errorf("can't read mnemonic: %w", err)
+		}
+		s, err := newHDSigner(w, p.Index)
+		if err != nil {
+			return nil, err
+		}
+		return s, nil
+	case p.External != "":
+		s, err := loadExternalSigner(p.External, p.Account)
+		if err != nil {
+			return nil, fmt.Errorf("can't open external signer: %w", err)
+		}
+		return s, nil
+	}
+	return nil, errNoSigner
+}
+
+// applyProfileSettings sets the gas, receipt, watch and output defaults of the profile
+func applyProfileSettings(p *profile) error {
+	transactDefaults = transactParams{gasLimit: p.GasLimit}
+	if p.GasPrice != "" {
+		gasPrice, ok := new(big.Int).SetString(p.GasPrice, 10)
+		if !ok {
+			return fmt.Errorf("invalid gas price: %s", p.GasPrice)
+		}
+		transactDefaults.gasPrice = gasPrice
+	}
+	if p.Receipt != nil {
+		timeout, err := time.ParseDuration(p.Receipt.Timeout)
+		if err != nil {
+			return fmt.Errorf("invalid receipt timeout: %w", err)
+		}
+		receiptConfig = receiptSettings{wait: p.Receipt.Wait, confirmations: p.Receipt.Confirmations, timeout: timeout}
+	}
+	if p.Watch != nil {
+		interval, err := time.ParseDuration(p.Watch.PollInterval)
+		if err != nil {
+			return fmt.Errorf("invalid poll interval: %w", err)
+		}
+		if interval <= 0 {
+			return errInvalidPollInterval
+		}
+		if p.Watch.Confirmations < 1 {
+			return errInvalidWatchConfirmations
+		}
+		watchConfig = watchSettings{poll: p.Watch.Poll, pollInterval: interval, confirmations: p.Watch.Confirmations}
+	}
+	if p.Output != "" {
+		return outputFormatValue{&outputFormat}.Set(p.Output)
+	}
+	return nil
+}
+
+// sessionProfile describes the current session as a profile
+func sessionProfile() *profile {
+	r := &profile{
+		URL:       sess.url,
+		Simulated: sess.sim != nil,
+		GasLimit:  transactDefaults.gasLimit,
+		Receipt: &profileReceipt{
+			Wait:          receiptConfig.wait,
+			Confirmations: receiptConfig.confirmations,
+			Timeout:       receiptConfig.timeout.String(),
+		},
+		Watch: &profileWatch{
+			Confirmations: watchConfig.confirmations,
+			Poll:          watchConfig.poll,
+			PollInterval:  watchConfig.pollInterval.String(),
+		},
+		Output: outputFormat,
+	}
+	if sess.sessionContract != nil {
+		r.Selected = sess.name
+	}
+	if sess.chainID != nil {
+		r.ChainID = sess.chainID.Uint64()
+	}
+	for _, i := range sess.contracts {
+		c := profileContract{Name: i.name, ABI: i.abiFile, Contract: i.artifact, Bytecode: i.bytecodeFile}
+		// simulated chains start empty, the contracts are deployed again
+		if i.address != (common.Address{}) && sess.sim == nil {
+			c.Address = i.address.Hex()
+		}
+		r.Contracts = append(r.Contracts, c)
+	}
+	if txSigner != nil {
+		r.Signer = txSigner.profile()
+	}
+	if transactDefaults.gasPrice != nil {
+		r.GasPrice = transactDefaults.gasPrice.String()
+	}
+	return r
+}
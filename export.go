@@ -0,0 +1,340 @@
+package main
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+const (
+	exportCSV     = "csv"
+	exportJSONL   = "jsonl"
+	exportParquet = "parquet"
+)
+
+var exportFormats = []string{exportCSV, exportJSONL, exportParquet}
+
+func checkExportFormat(format string) error {
+	for _, i := range exportFormats {
+		if format == i {
+			return nil
+		}
+	}
+	return fmt.Errorf("invalid export format (expecting %s)", strings.Join(exportFormats, ", "))
+}
+
+// column kinds of the exported events
+const (
+	columnInt = iota
+	columnUint
+	columnBool
+	columnString
+	columnJSON
+	columnTimestamp
+)
+
+type exportColumn struct {
+	name string
+	kind int
+	typ  abi.Type
+}
+
+// exportColumns returns the log columns followed by one column per event input
+func exportColumns(ev *abi.Event) []exportColumn {
+	r := []exportColumn{
+		{name: "block_number", kind: columnInt},
+		{name: "block_timestamp", kind: columnTimestamp},
+		{name: "tx_hash", kind: columnString},
+		{name: "log_index", kind: columnInt},
+	}
+	for n, i := range ev.Inputs {
+		r = append(r, exportColumn{name: argumentName(n, i), kind: columnKind(i), typ: i.Type})
+	}
+	return r
+}
+
+func columnKind(arg abi.Argument) int {
+	switch arg.Type.T {
+	case abi.IntTy:
+		if arg.Type.Size <= 64 {
+			return columnInt
+		}
+	case abi.UintTy:
+		if arg.Type.Size < 64 {
+			return columnInt
+		} else if arg.Type.Size == 64 {
+			return columnUint
+		}
+	case abi.BoolTy:
+		return columnBool
+	case abi.SliceTy, abi.ArrayTy, abi.TupleTy:
+		// indexed dynamic values are only available as hashes
+		if !arg.Indexed {
+			return columnJSON
+		}
+	}
+	return columnString
+}
+
+// exportValue converts an abi value to the go type of the column kind
+func exportValue(c exportColumn, v interface{}) interface{} {
+	switch c.kind {
+	case columnInt:
+		rv := reflect.ValueOf(v)
+		if rv.Kind() >= reflect.Uint && rv.Kind() <= reflect.Uint64 {
+			return int64(rv.Uint())
+		}
+		return rv.Int()
+	case columnUint:
+		return reflect.ValueOf(v).Uint()
+	case columnBool:
+		return v.(bool)
+	case columnJSON:
+		return jsonValue(c.typ, v)
+	}
+	switch vv := v.(type) {
+	case string:
+		return vv
+	case *big.Int:
+		return vv.String()
+	}
+	return fmt.Sprint(formatValue(v))
+}
+
+// eventExporter writes the exported rows to a file
+type eventExporter interface {
+	write(row []interface{}) error
+	close() error
+}
+
+func newEventExporter(format, fn string, columns []exportColumn) (eventExporter, error) {
+	f, err := os.Create(fn)
+	if err != nil {
+		return nil, err
+	}
+	switch format {
+	case exportCSV:
+		w := csv.NewWriter(f)
+		header := make([]string, 0, len(columns))
+		for _, i := range columns {
+			header = append(header, i.name)
+		}
+		if err = w.Write(header); err != nil {
+			f.Close()
+			return nil, err
+		}
+		return &csvExporter{f: f, w: w, columns: columns}, nil
+	case exportJSONL:
+		return &jsonlExporter{f: f, w: bufio.NewWriter(f), columns: columns}, nil
+	default:
+		pc := make([]*parquetColumn, 0, len(columns))
+		for _, i := range columns {
+			pc = append(pc, newParquetColumn(i))
+		}
+		w, err := newParquetWriter(f, pc)
+		if err != nil {
+			f.Close()
+			return nil, err
+		}
+		return &parquetExporter{w: w, columns: columns}, nil
+	}
+}
+
+func formatTimestamp(t time.Time) string { return t.UTC().Format(time.RFC3339) }
+
+type csvExporter struct {
+	f       *os.File
+	w       *csv.Writer
+	columns []exportColumn
+}
+
+func (e *csvExporter) write(row []interface{}) error {
+	r := make([]string, 0, len(row))
+	for n, i := range row {
+		switch e.columns[n].kind {
+		case columnInt:
+			r = append(r, strconv.FormatInt(i.(int64), 10))
+		case columnUint:
+			r = append(r, strconv.FormatUint(i.(uint64), 10))
+		case columnBool:
+			r = append(r, strconv.FormatBool(i.(bool)))
+		case columnTimestamp:
+			r = append(r, formatTimestamp(i.(time.Time)))
+		case columnJSON:
+			b, err := json.Marshal(i)
+			if err != nil {
+				return err
+			}
+			r = append(r, string(b))
+		default:
+			r = append(r, i.(string))
+		}
+	}
+	return e.w.Write(r)
+}
+
+func (e *csvExporter) close() error {
+	e.w.Flush()
+	if err := e.w.Error(); err != nil {
+		e.f.Close()
+		return err
+	}
+	return e.f.Close()
+}
+
+// jsonlExporter writes an object per line, keeping the column order
+type jsonlExporter struct {
+	f       *os.File
+	w       *bufio.Writer
+	columns []exportColumn
+}
+
+func (e *jsonlExporter) write(row []interface{}) error {
+	e.w.WriteByte('{')
+	for n, i := range row {
+		if n > 0 {
+			e.w.WriteByte(',')
+		}
+		if t, ok := i.(time.Time); ok {
+			i = formatTimestamp(t)
+		}
+		k, _ := json.Marshal(e.columns[n].name)
+		v, err := json.Marshal(i)
+		if err != nil {
+			return err
+		}
+		e.w.Write(k)
+		e.w.WriteByte(':')
+		e.w.Write(v)
+	}
+	_, err := e.w.WriteString("}\n")
+	return err
+}
+
+func (e *jsonlExporter) close() error {
+	if err := e.w.Flush(); err != nil {
+		e.f.Close()
+		return err
+	}
+	return e.f.Close()
+}
+
+func newParquetColumn(c exportColumn) *parquetColumn {
+	r := &parquetColumn{name: c.name, typ: parquetInt64, converted: -1}
+	switch c.kind {
+	case columnUint:
+		r.converted = parquetUint64
+	case columnBool:
+		r.typ = parquetBoolean
+	case columnString:
+		r.typ, r.converted = parquetByteArray, parquetUTF8
+	case columnJSON:
+		r.typ, r.converted = parquetByteArray, parquetJSON
+	case columnTimestamp:
+		r.converted = parquetTimestampMillis
+	}
+	return r
+}
+
+type parquetExporter struct {
+	w       *parquetWriter
+	columns []exportColumn
+}
+
+func (e *parquetExporter) write(row []interface{}) error {
+	for n, i := range row {
+		c := e.w.columns[n]
+		switch e.columns[n].kind {
+		case columnInt:
+			c.appendInt64(i.(int64))
+		case columnUint:
+			c.appendInt64(int64(i.(uint64)))
+		case columnBool:
+			c.appendBool(i.(bool))
+		case columnTimestamp:
+			c.appendInt64(i.(time.Time).UnixNano() / int64(time.Millisecond))
+		case columnJSON:
+			b, err := json.Marshal(i)
+			if err != nil {
+				return err
+			}
+			c.appendBytes(b)
+		default:
+			c.appendBytes([]byte(i.(string)))
+		}
+	}
+	return e.w.endRow()
+}
+
+func (e *parquetExporter) close() error { return e.w.close() }
+
+// exportEvents writes the decoded logs of the event in the block range to the file
+func exportEvents(cl backend, addr *common.Address, contractABI *abi.ABI, name string, filters [][]interface{}, start, last uint64, format, fn string) error {
+	ev := contractABI.Events[name]
+	query, err := eventQuery(addr, &ev, filters)
+	if err != nil {
+		return err
+	}
+	columns := exportColumns(&ev)
+	e, err := newEventExporter(format, fn, columns)
+	if err != nil {
+		return err
+	}
+	bc := bind.NewBoundContract(*addr, *contractABI, cl, cl, cl)
+	ctx, cancel := interruptContext()
+	defer cancel()
+	// logs arrive in block order, so the last header is enough to avoid refetching it
+	var (
+		count     int
+		lastBlock = ^uint64(0)
+		timestamp time.Time
+	)
+	next, err := scanLogs(ctx, cl, query, start, last, func(l types.Log) error {
+		eventData := make(map[string]interface{}, 8)
+		if err := bc.UnpackLogIntoMap(eventData, name, l); err != nil {
+			return err
+		}
+		if l.BlockNumber != lastBlock {
+			h, err := cl.HeaderByNumber(ctx, new(big.Int).SetUint64(l.BlockNumber))
+			if err != nil {
+				return fmt.Errorf("can't get block %d: %w", l.BlockNumber, err)
+			}
+			lastBlock, timestamp = l.BlockNumber, time.Unix(int64(h.Time), 0)
+		}
+		row := []interface{}{int64(l.BlockNumber), timestamp, l.TxHash.Hex(), int64(l.Index)}
+		for n, i := range ev.Inputs {
+			row = append(row, exportValue(columns[n+4], eventData[i.Name]))
+		}
+		if err := e.write(row); err != nil {
+			return err
+		}
+		count++
+		return nil
+	})
+	if cerr := e.close(); cerr != nil && err == nil {
+		err = cerr
+	}
+	if ctx.Err() != nil {
+		infof("interrupted, blocks %d-%d not exported, %d events exported to %s\n", next, last, count, fn)
+		return errAborted
+	} else if err != nil {
+		return fmt.Errorf("error exporting logs (blocks %d-%d not exported): %w", next, last, err)
+	}
+	infof("%d events exported to %s\n", count, fn)
+	return nil
+}
+
+// exportFileName is the default file of the event export
+func exportFileName(name, format string) string { return name + "." + format }
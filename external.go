@@ -0,0 +1,97 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+
+	"github.com/c-bata/go-prompt"
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/accounts/external"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+var errNoExternalAccounts = errors.New("the external signer has no accounts")
+
+// defaultExternalSigner returns the ipc endpoint of clef
+func defaultExternalSigner() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".clef", "clef.ipc")
+}
+
+// externalSigner asks a clef compatible signer to sign the transactions. the keys never
+// enter the process
+type externalSigner struct {
+	signer   *external.ExternalSigner
+	endpoint string
+	account  accounts.Account
+}
+
+// openExternalSigner connects to the signer at the ipc path or http url
+func openExternalSigner(endpoint string) (*external.ExternalSigner, []accounts.Account, error) {
+	r, err := external.NewExternalSigner(endpoint)
+	if err != nil {
+		return nil, nil, err
+	}
+	accs := r.Accounts()
+	if len(accs) == 0 {
+		r.Close()
+		return nil, nil, errNoExternalAccounts
+	}
+	return r, accs, nil
+}
+
+// loadExternalSigner connects to the signer and uses the account, or the only account
+func loadExternalSigner(endpoint, address string) (*externalSigner, error) {
+	ext, accs, err := openExternalSigner(endpoint)
+	if err != nil {
+		return nil, err
+	}
+	if address == "" && len(accs) == 1 {
+		return &externalSigner{signer: ext, endpoint: endpoint, account: accs[0]}, nil
+	}
+	for _, i := range accs {
+		if common.IsHexAddress(address) && i.Address == common.HexToAddress(address) {
+			return &externalSigner{signer: ext, endpoint: endpoint, account: i}, nil
+		}
+	}
+	ext.Close()
+	return nil, fmt.Errorf("%w: %s", errUnknownAccount, address)
+}
+
+func externalAccountSuggestions(accs []accounts.Account) []prompt.Suggest {
+	r := make([]prompt.Suggest, 0, len(accs))
+	for _, i := range accs {
+		r = append(r, prompt.Suggest{Text: i.Address.Hex()})
+	}
+	return r
+}
+
+func (s *externalSigner) address() common.Address { return s.account.Address }
+
+func (s *externalSigner) transactor(chainID *big.Int) (*bind.TransactOpts, error) {
+	return &bind.TransactOpts{
+		From: s.account.Address,
+		Signer: func(addr common.Address, tx *types.Transaction) (*types.Transaction, error) {
+			if addr != s.account.Address {
+				return nil, bind.ErrNotAuthorized
+			}
+			return s.signer.SignTx(s.account, tx, chainID)
+		},
+		Context: context.Background(),
+	}, nil
+}
+
+func (s *externalSigner) profile() *profileSigner {
+	return &profileSigner{External: s.endpoint, Account: s.account.Address.Hex()}
+}
+
+func (s *externalSigner) close() { s.signer.Close() }
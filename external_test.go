@@ -0,0 +1,106 @@
+package main
+
+import (
+	"crypto/ecdsa"
+	"errors"
+	"math/big"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
+)
+
+// mockClef is a clef compatible signer holding a single key
+type mockClef struct{ key *ecdsa.PrivateKey }
+
+func (m *mockClef) Version() string { return "6.1.0" }
+
+func (m *mockClef) List() []common.Address {
+	return []common.Address{crypto.PubkeyToAddress(m.key.PublicKey)}
+}
+
+func (m *mockClef) SignTransaction(args apitypes.SendTxArgs) (map[string]interface{}, error) {
+	if args.From.Address() != crypto.PubkeyToAddress(m.key.PublicKey) {
+		return nil, errors.New("unknown account")
+	}
+	tx, err := types.SignTx(args.ToTransaction(), types.LatestSignerForChainID((*big.Int)(args.ChainID)), m.key)
+	if err != nil {
+		return nil, err
+	}
+	raw, err := tx.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{"raw": hexutil.Bytes(raw), "tx": tx}, nil
+}
+
+// startMockClef serves the mock signer over http
+func startMockClef(t *testing.T) (string, common.Address) {
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := rpc.NewServer()
+	if err = srv.RegisterName("account", &mockClef{key: key}); err != nil {
+		t.Fatal(err)
+	}
+	hs := httptest.NewServer(srv)
+	t.Cleanup(func() {
+		hs.Close()
+		srv.Stop()
+	})
+	return hs.URL, crypto.PubkeyToAddress(key.PublicKey)
+}
+
+func TestExternalSignerTransactor(t *testing.T) {
+	url, addr := startMockClef(t)
+	s, err := loadExternalSigner(url, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.close()
+	if s.address() != addr {
+		t.Fatalf("expecting account %s, got %s", addr.Hex(), s.address().Hex())
+	}
+	chainID := big.NewInt(1337)
+	opts, err := s.transactor(chainID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	to := common.HexToAddress("0x1111111111111111111111111111111111111111")
+	for _, i := range []types.TxData{
+		&types.LegacyTx{Nonce: 1, GasPrice: big.NewInt(1e9), Gas: 21000, To: &to, Value: big.NewInt(1)},
+		&types.DynamicFeeTx{ChainID: chainID, Nonce: 2, GasTipCap: big.NewInt(1), GasFeeCap: big.NewInt(2e9), Gas: 50000, To: &to, Data: []byte{1, 2, 3}},
+	} {
+		tx, err := opts.Signer(opts.From, types.NewTx(i))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if tx.ChainId().Cmp(chainID) != 0 {
+			t.Errorf("tx type %d: expecting chain id %s, got %s", tx.Type(), chainID, tx.ChainId())
+		}
+		sender, err := types.Sender(types.LatestSignerForChainID(chainID), tx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if sender != addr {
+			t.Errorf("tx type %d: expecting sender %s, got %s", tx.Type(), addr.Hex(), sender.Hex())
+		}
+	}
+	if _, err = opts.Signer(to, types.NewTx(&types.LegacyTx{To: &to})); err == nil {
+		t.Error("signed for another account")
+	}
+}
+
+func TestExternalSignerUnknownAccount(t *testing.T) {
+	url, _ := startMockClef(t)
+	_, err := loadExternalSigner(url, "0x1111111111111111111111111111111111111111")
+	if !errors.Is(err, errUnknownAccount) {
+		t.Errorf("expecting %v, got %v", errUnknownAccount, err)
+	}
+}
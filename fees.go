@@ -0,0 +1,178 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+	"strings"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// blocks and percentile of the priority fees used to suggest the tip
+const (
+	feeHistoryBlocks     = 20
+	feeHistoryPercentile = 50
+)
+
+var (
+	errNoLondon    = errors.New("chain doesn't support dynamic fees (eip-1559)")
+	errMixedFees   = errors.New("use either a gas price or dynamic fees")
+	errFeeBelowTip = errors.New("max fee is lower than the priority fee")
+)
+
+// feeHistoryReader is implemented by the clients with eth_feeHistory
+type feeHistoryReader interface {
+	FeeHistory(ctx context.Context, blockCount uint64, lastBlock *big.Int, rewardPercentiles []float64) (*ethereum.FeeHistory, error)
+}
+
+// feeSuggestion is the current pricing of the chain. chains without london only have a gas price
+type feeSuggestion struct {
+	baseFee  *big.Int
+	tipCap   *big.Int
+	feeCap   *big.Int
+	gasPrice *big.Int
+}
+
+func (f *feeSuggestion) london() bool { return f.baseFee != nil }
+
+func suggestFees(cl backend) (*feeSuggestion, error) {
+	h, err := cl.HeaderByNumber(context.Background(), nil)
+	if err != nil {
+		return nil, err
+	}
+	if h.BaseFee == nil {
+		gasPrice, err := cl.SuggestGasPrice(context.Background())
+		if err != nil {
+			return nil, err
+		}
+		return &feeSuggestion{gasPrice: gasPrice}, nil
+	}
+	tipCap, err := suggestTipCap(cl)
+	if err != nil {
+		return nil, err
+	}
+	return &feeSuggestion{baseFee: h.BaseFee, tipCap: tipCap, feeCap: maxFee(h.BaseFee, tipCap)}, nil
+}
+
+// maxFee leaves room for the base fee to double
+func maxFee(baseFee, tipCap *big.Int) *big.Int {
+	return new(big.Int).Add(new(big.Int).Mul(baseFee, big.NewInt(2)), tipCap)
+}
+
+// suggestTipCap returns the median of the priority fees paid in the last blocks, or the
+// suggestion of the client when there's no fee history
+func suggestTipCap(cl backend) (*big.Int, error) {
+	if fh, ok := cl.(feeHistoryReader); ok {
+		h, err := fh.FeeHistory(context.Background(), feeHistoryBlocks, nil, []float64{feeHistoryPercentile})
+		if err == nil {
+			tips := make([]*big.Int, 0, len(h.Reward))
+			for _, i := range h.Reward {
+				if len(i) > 0 && i[0] != nil && i[0].Sign() > 0 {
+					tips = append(tips, i[0])
+				}
+			}
+			if len(tips) > 0 {
+				sort.Slice(tips, func(i, j int) bool { return tips[i].Cmp(tips[j]) < 0 })
+				return tips[len(tips)/2], nil
+			}
+		}
+	}
+	return cl.SuggestGasTipCap(context.Background())
+}
+
+// applyFees sets the gas price or the dynamic fees of the transaction, filling what wasn't given
+func applyFees(cl backend, opts *bind.TransactOpts, params *transactParams) (*feeSuggestion, error) {
+	dynamic := params.feeCap != nil || params.tipCap != nil
+	if params.gasPrice != nil {
+		if dynamic {
+			return nil, errMixedFees
+		}
+		opts.GasPrice = params.gasPrice
+		return &feeSuggestion{gasPrice: params.gasPrice}, nil
+	}
+	fees, err := suggestFees(cl)
+	if err != nil {
+		return nil, fmt.Errorf("can't suggest fees: %w", err)
+	}
+	if !fees.london() {
+		if dynamic {
+			return nil, errNoLondon
+		}
+		opts.GasPrice = fees.gasPrice
+		return fees, nil
+	}
+	opts.GasTipCap = fees.tipCap
+	if params.tipCap != nil {
+		opts.GasTipCap = params.tipCap
+	}
+	opts.GasFeeCap = maxFee(fees.baseFee, opts.GasTipCap)
+	if params.feeCap != nil {
+		opts.GasFeeCap = params.feeCap
+	}
+	if opts.GasFeeCap.Cmp(opts.GasTipCap) < 0 {
+		return nil, errFeeBelowTip
+	}
+	return fees, nil
+}
+
+// bumpFee raises a fee by the 10% the nodes require to replace a pending transaction
+func bumpFee(fee *big.Int) *big.Int {
+	r := new(big.Int).Mul(fee, big.NewInt(110))
+	return r.Add(r.Div(r, big.NewInt(100)), big.NewInt(1))
+}
+
+func maxBig(a, b *big.Int) *big.Int {
+	if a.Cmp(b) > 0 {
+		return a
+	}
+	return b
+}
+
+// replacementFees returns the fees to replace tx: 10% above its fees and at least the current suggestion
+func replacementFees(cl backend, tx *types.Transaction) (*transactParams, error) {
+	fees, err := suggestFees(cl)
+	if err != nil {
+		return nil, fmt.Errorf("can't suggest fees: %w", err)
+	}
+	if tx.Type() == types.LegacyTxType {
+		price := bumpFee(tx.GasPrice())
+		if fees.london() {
+			return &transactParams{gasPrice: maxBig(price, maxFee(fees.baseFee, fees.tipCap))}, nil
+		}
+		return &transactParams{gasPrice: maxBig(price, fees.gasPrice)}, nil
+	}
+	if !fees.london() {
+		return nil, errNoLondon
+	}
+	tipCap := maxBig(bumpFee(tx.GasTipCap()), fees.tipCap)
+	return &transactParams{tipCap: tipCap, feeCap: maxBig(bumpFee(tx.GasFeeCap()), maxFee(fees.baseFee, tipCap))}, nil
+}
+
+// worstCaseCost is the most the transaction can cost: the value plus all the gas at the max fee
+func worstCaseCost(opts *bind.TransactOpts) *big.Int {
+	price := opts.GasPrice
+	if price == nil {
+		price = opts.GasFeeCap
+	}
+	r := new(big.Int).Mul(price, new(big.Int).SetUint64(opts.GasLimit))
+	if opts.Value != nil {
+		r.Add(r, opts.Value)
+	}
+	return r
+}
+
+func formatFees(b *strings.Builder, opts *bind.TransactOpts, fees *feeSuggestion) {
+	fmt.Fprintf(b, "  gas limit: %d\n", opts.GasLimit)
+	if opts.GasPrice != nil {
+		fmt.Fprintf(b, "  gas price: %s gwei\n", formatGwei(opts.GasPrice))
+		return
+	}
+	fmt.Fprintf(b, "  base fee: %s gwei\n", formatGwei(fees.baseFee))
+	fmt.Fprintf(b, "  max priority fee: %s gwei\n", formatGwei(opts.GasTipCap))
+	fmt.Fprintf(b, "  max fee: %s gwei\n", formatGwei(opts.GasFeeCap))
+}
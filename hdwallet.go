@@ -0,0 +1,211 @@
+package main
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/hmac"
+	"crypto/sha512"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"path/filepath"
+	"strings"
+
+	"github.com/c-bata/go-prompt"
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/tyler-smith/go-bip39"
+)
+
+// defaultHDPath is the derivation path of the accounts, i is the account index
+const defaultHDPath = "m/44'/60'/0'/0/i"
+
+var (
+	errInvalidMnemonic = errors.New("invalid mnemonic")
+	errInvalidHDPath   = errors.New("derivation path must end with /i")
+	errInvalidChildKey = errors.New("invalid child key, use another index")
+	errNotHDWallet     = errors.New("the signer isn't a mnemonic")
+)
+
+// hdWallet derives the accounts of a bip-39 mnemonic along a bip-32 path
+type hdWallet struct {
+	key       []byte
+	chainCode []byte
+	// path without the account index
+	base accounts.DerivationPath
+	// number of accounts listed
+	count int
+	// files the mnemonic and passphrase were read from, saved in the profiles
+	mnemonicFile   string
+	passphraseFile string
+}
+
+func newHDWallet(mnemonic, passphrase, path string) (*hdWallet, error) {
+	mnemonic = strings.Join(strings.Fields(mnemonic), " ")
+	seed, err := bip39.NewSeedWithErrorChecking(mnemonic, passphrase)
+	if err != nil {
+		return nil, errInvalidMnemonic
+	}
+	if !strings.HasSuffix(path, "/i") {
+		return nil, errInvalidHDPath
+	}
+	base, err := accounts.ParseDerivationPath(strings.TrimSuffix(path, "/i"))
+	if err != nil {
+		return nil, err
+	}
+	mac := hmac.New(sha512.New, []byte("Bitcoin seed"))
+	mac.Write(seed)
+	sum := mac.Sum(nil)
+	return &hdWallet{key: sum[:32], chainCode: sum[32:], base: base, count: 10}, nil
+}
+
+// readHDWallet reads the mnemonic and the optional passphrase from files
+func readHDWallet(mnemonicFile, passphraseFile, path string) (*hdWallet, error) {
+	mnemonicFile, err := filepath.Abs(mnemonicFile)
+	if err != nil {
+		return nil, err
+	}
+	b, err := ioutil.ReadFile(mnemonicFile)
+	if err != nil {
+		return nil, err
+	}
+	var passphrase string
+	if passphraseFile != "" {
+		if passphraseFile, err = filepath.Abs(passphraseFile); err != nil {
+			return nil, err
+		}
+		pb, err := ioutil.ReadFile(passphraseFile)
+		if err != nil {
+			return nil, err
+		}
+		passphrase = strings.TrimRight(string(pb), "\r\n")
+	}
+	if path == "" {
+		path = defaultHDPath
+	}
+	r, err := newHDWallet(string(b), passphrase, path)
+	if err != nil {
+		return nil, err
+	}
+	r.mnemonicFile, r.passphraseFile = mnemonicFile, passphraseFile
+	return r, nil
+}
+
+func (w *hdWallet) path() string { return w.base.String() + "/i" }
+
+// deriveKey derives the key of the account index
+func (w *hdWallet) deriveKey(index uint32) (*ecdsa.PrivateKey, error) {
+	key, chainCode := w.key, w.chainCode
+	for _, i := range append(append(accounts.DerivationPath{}, w.base...), index) {
+		var err error
+		if key, chainCode, err = deriveChild(key, chainCode, i); err != nil {
+			return nil, err
+		}
+	}
+	return crypto.ToECDSA(key)
+}
+
+// deriveChild derives a bip-32 private child key
+func deriveChild(key, chainCode []byte, index uint32) ([]byte, []byte, error) {
+	var data []byte
+	if index >= 0x80000000 {
+		data = append([]byte{0}, key...)
+	} else {
+		k, err := crypto.ToECDSA(key)
+		if err != nil {
+			return nil, nil, err
+		}
+		data = crypto.CompressPubkey(&k.PublicKey)
+	}
+	data = append(data, 0, 0, 0, 0)
+	binary.BigEndian.PutUint32(data[len(data)-4:], index)
+	mac := hmac.New(sha512.New, chainCode)
+	mac.Write(data)
+	sum := mac.Sum(nil)
+	n := crypto.S256().Params().N
+	il := new(big.Int).SetBytes(sum[:32])
+	if il.Cmp(n) >= 0 {
+		return nil, nil, errInvalidChildKey
+	}
+	child := il.Add(il, new(big.Int).SetBytes(key))
+	child.Mod(child, n)
+	if child.Sign() == 0 {
+		return nil, nil, errInvalidChildKey
+	}
+	return common.LeftPadBytes(child.Bytes(), 32), sum[32:], nil
+}
+
+// accountSuggestions lists the first accounts of the wallet with their balances
+func (w *hdWallet) accountSuggestions(cl backend) ([]prompt.Suggest, error) {
+	r := make([]prompt.Suggest, 0, w.count)
+	for i := 0; i < w.count; i++ {
+		k, err := w.deriveKey(uint32(i))
+		if err != nil {
+			return nil, err
+		}
+		addr := crypto.PubkeyToAddress(k.PublicKey)
+		balance, err := cl.BalanceAt(context.Background(), addr, nil)
+		if err != nil {
+			return nil, err
+		}
+		r = append(r, prompt.Suggest{Text: addr.Hex(), Description: fmt.Sprintf("%d  %s ether", i, formatEther(balance))})
+	}
+	return r, nil
+}
+
+// chooseHDAccount asks for one of the listed accounts and returns its signer
+func chooseHDAccount(w *hdWallet, current uint32) (*hdSigner, error) {
+	choices, err := w.accountSuggestions(sess.client)
+	if err != nil {
+		return nil, err
+	}
+	showSuggestions(choices)
+	def := ""
+	if int(current) < len(choices) {
+		def = choices[current].Text
+	}
+	addr, ok := inputMultiChoice("sign with account (%s): ", def, choices, showSuggestions)
+	if !ok {
+		return nil, errAborted
+	}
+	for n, i := range choices {
+		if i.Text == addr {
+			return newHDSigner(w, uint32(n))
+		}
+	}
+	return nil, errUnknownAccount
+}
+
+// hdSigner signs with an account derived from the wallet
+type hdSigner struct {
+	wallet *hdWallet
+	index  uint32
+	key    *ecdsa.PrivateKey
+}
+
+func newHDSigner(w *hdWallet, index uint32) (*hdSigner, error) {
+	key, err := w.deriveKey(index)
+	if err != nil {
+		return nil, err
+	}
+	return &hdSigner{wallet: w, index: index, key: key}, nil
+}
+
+func (s *hdSigner) address() common.Address { return crypto.PubkeyToAddress(s.key.PublicKey) }
+
+func (s *hdSigner) transactor(chainID *big.Int) (*bind.TransactOpts, error) {
+	return bind.NewKeyedTransactorWithChainID(s.key, chainID)
+}
+
+func (s *hdSigner) profile() *profileSigner {
+	if s.wallet.mnemonicFile == "" {
+		return nil
+	}
+	return &profileSigner{Mnemonic: s.wallet.mnemonicFile, HDPath: s.wallet.path(), Index: s.index, PasswordFile: s.wallet.passphraseFile}
+}
+
+func (s *hdSigner) close() {}
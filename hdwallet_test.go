@@ -0,0 +1,88 @@
+package main
+
+import (
+	"encoding/hex"
+	"errors"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+const testMnemonic = "test test test test test test test test test test test junk"
+
+func TestHDWalletAccounts(t *testing.T) {
+	w, err := newHDWallet(testMnemonic, "", defaultHDPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for n, i := range []string{
+		"0xf39Fd6e51aad88F6F4ce6aB8827279cffFb92266",
+		"0x70997970C51812dc3A010C7d01b50e0d17dc79C8",
+		"0x3C44CdDdB6a900fa2b585dd299e03d12FA4293BC",
+	} {
+		k, err := w.deriveKey(uint32(n))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if addr := crypto.PubkeyToAddress(k.PublicKey).Hex(); addr != i {
+			t.Errorf("account %d: expecting %s, got %s", n, i, addr)
+		}
+	}
+}
+
+func TestHDWalletMnemonicSpaces(t *testing.T) {
+	w, err := newHDWallet("  test test test test test test\ntest test test test test junk\n", "", defaultHDPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	k, err := w.deriveKey(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if addr := crypto.PubkeyToAddress(k.PublicKey).Hex(); addr != "0xf39Fd6e51aad88F6F4ce6aB8827279cffFb92266" {
+		t.Errorf("unexpected account %s", addr)
+	}
+}
+
+func TestHDWalletErrors(t *testing.T) {
+	for _, i := range []struct {
+		mnemonic, path string
+		err            error
+	}{
+		{testMnemonic, "m/44'/60'/0'/0/0", errInvalidHDPath},
+		{testMnemonic, "m/44'/60'/0'/0", errInvalidHDPath},
+		{"test test test test test test test test test test test test", defaultHDPath, errInvalidMnemonic},
+	} {
+		if _, err := newHDWallet(i.mnemonic, "", i.path); !errors.Is(err, i.err) {
+			t.Errorf("%s %s: expecting %v, got %v", i.mnemonic, i.path, i.err, err)
+		}
+	}
+}
+
+// bip-32 test vector 1, m/0'/1 from the master key
+func TestDeriveChild(t *testing.T) {
+	decode := func(s string) []byte {
+		b, err := hex.DecodeString(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return b
+	}
+	key := decode("e8f32e723decf4051aefac8e2c93c9c5b214313817cdb01a1494b917c8436b35")
+	chainCode := decode("873dff81c02f525623fd1fe5167eac3a55a049de3d314bb42ee227ffed37d508")
+	for _, i := range []struct {
+		index          uint32
+		key, chainCode string
+	}{
+		{0x80000000, "edb2e14f9ee77d26dd93b4ecede8d16ed408ce149b6cd80b0715a2d911a0afea", "47fdacbd0f1097043b78c63c20c34ef4ed9a111d980047ad16282c7ae6236141"},
+		{1, "3c6cb8d0f6a264c91ea8b5030fadaa8e538b020f0a387421a12de9319dc93368", "2a7857631386ba23dacac34180dd1983734e444fdbf774041578e9b6adb37c19"},
+	} {
+		var err error
+		if key, chainCode, err = deriveChild(key, chainCode, i.index); err != nil {
+			t.Fatal(err)
+		}
+		if hex.EncodeToString(key) != i.key || hex.EncodeToString(chainCode) != i.chainCode {
+			t.Fatalf("child %#x: got key %x chain code %x", i.index, key, chainCode)
+		}
+	}
+}
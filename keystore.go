@@ -0,0 +1,159 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/c-bata/go-prompt"
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/accounts/keystore"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+var (
+	errNoKeystoreAccounts = errors.New("no accounts in keystore")
+	errUnknownAccount     = errors.New("unknown account")
+)
+
+// defaultKeystoreDir returns the keystore of a default geth datadir
+func defaultKeystoreDir() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".ethereum", "keystore")
+}
+
+// openKeystore opens a geth keystore directory, which must exist and hold accounts
+func openKeystore(dir string) (*keystore.KeyStore, error) {
+	info, err := os.Stat(dir)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("%s: not a directory", dir)
+	}
+	ks := keystore.NewKeyStore(dir, keystore.StandardScryptN, keystore.StandardScryptP)
+	if len(ks.Accounts()) == 0 {
+		return nil, fmt.Errorf("%w: %s", errNoKeystoreAccounts, dir)
+	}
+	return ks, nil
+}
+
+func findKeystoreAccount(ks *keystore.KeyStore, address string) (accounts.Account, error) {
+	if !common.IsHexAddress(address) {
+		return accounts.Account{}, fmt.Errorf("%w: %s", errUnknownAccount, address)
+	}
+	r, err := ks.Find(accounts.Account{Address: common.HexToAddress(address)})
+	if err != nil {
+		return accounts.Account{}, fmt.Errorf("%w: %s", errUnknownAccount, address)
+	}
+	return r, nil
+}
+
+// keystoreAccountSuggestions lists the accounts of the keystore with their key files
+func keystoreAccountSuggestions(ks *keystore.KeyStore) []prompt.Suggest {
+	r := make([]prompt.Suggest, 0, len(ks.Accounts()))
+	for _, i := range ks.Accounts() {
+		r = append(r, prompt.Suggest{Text: i.Address.Hex(), Description: filepath.Base(i.URL.Path)})
+	}
+	return r
+}
+
+// keystoreSigner signs with an account of a keystore directory, unlocked for unlockTimeout
+// (0 until the session ends)
+type keystoreSigner struct {
+	keystore      *keystore.KeyStore
+	dir           string
+	account       accounts.Account
+	passwordFile  string
+	unlockTimeout time.Duration
+}
+
+func newKeystoreSigner(ks *keystore.KeyStore, dir string, account accounts.Account) *keystoreSigner {
+	return &keystoreSigner{keystore: ks, dir: dir, account: account}
+}
+
+// loadKeystoreSigner unlocks the keystore account with the password file, or asks for the
+// password in the console
+func loadKeystoreSigner(dir, address, passwordFile string) (*keystoreSigner, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+	ks, err := openKeystore(dir)
+	if err != nil {
+		return nil, err
+	}
+	account, err := findKeystoreAccount(ks, address)
+	if err != nil {
+		return nil, err
+	}
+	r := newKeystoreSigner(ks, dir, account)
+	if passwordFile != "" {
+		if r.passwordFile, err = filepath.Abs(passwordFile); err != nil {
+			return nil, err
+		}
+	} else if !interactive {
+		return nil, errors.New("keystore account needs a password file")
+	}
+	if err = r.unlock(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// unlock unlocks the keystore account for the unlock timeout, reading the password from the
+// password file or asking for it
+func (s *keystoreSigner) unlock() error {
+	var password string
+	if s.passwordFile != "" {
+		b, err := ioutil.ReadFile(s.passwordFile)
+		if err != nil {
+			return err
+		}
+		password = strings.TrimRight(string(b), "\r\n")
+	} else {
+		fmt.Printf("unlock account %s\n", s.account.Address.Hex())
+		var err error
+		if password, err = inputPassword(); err != nil {
+			return err
+		}
+		fmt.Println()
+	}
+	return s.keystore.TimedUnlock(s.account, password, s.unlockTimeout)
+}
+
+// unlocked reports whether the keystore still holds the decrypted key
+func (s *keystoreSigner) unlocked() bool {
+	_, err := s.keystore.SignHash(s.account, make([]byte, 32))
+	return !errors.Is(err, keystore.ErrLocked)
+}
+
+func (s *keystoreSigner) address() common.Address { return s.account.Address }
+
+// transactor unlocks the account again once the unlock timeout wiped the key
+func (s *keystoreSigner) transactor(chainID *big.Int) (*bind.TransactOpts, error) {
+	if !s.unlocked() {
+		if !interactive && s.passwordFile == "" {
+			return nil, keystore.ErrLocked
+		}
+		if err := s.unlock(); err != nil {
+			return nil, err
+		}
+	}
+	return bind.NewKeyStoreTransactorWithChainID(s.keystore, s.account, chainID)
+}
+
+func (s *keystoreSigner) profile() *profileSigner {
+	return &profileSigner{Keystore: s.dir, Account: s.account.Address.Hex(), PasswordFile: s.passwordFile}
+}
+
+func (s *keystoreSigner) close() { s.keystore.Lock(s.account.Address) }
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"strings"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rpc"
+	"golang.org/x/crypto/ssh/terminal"
+)
+
+// block ranges of the log queries. the range shrinks down to one block when the provider
+// refuses it and grows back while the queries succeed
+const (
+	logChunkBlocks    = 2000
+	logChunkMaxBlocks = 100000
+)
+
+// rangeLimitErrors are the errors providers return for ranges with too many blocks or results
+var rangeLimitErrors = []string{
+	"query returned more than",   // geth, infura
+	"exceed maximum block range", // bsc, polygon
+	"log response size exceeded", // alchemy
+	"block range is too wide",    // ankr
+	"eth_getlogs is limited to",  // quicknode
+	"query timeout exceeded",     // erigon
+}
+
+// limitExceededCode is the eip-1474 error code of requests over the provider limits
+const limitExceededCode = -32005
+
+func isRangeLimitError(err error) bool {
+	var re rpc.Error
+	if errors.As(err, &re) && re.ErrorCode() == limitExceededCode {
+		return true
+	}
+	msg := strings.ToLower(err.Error())
+	for _, i := range rangeLimitErrors {
+		if strings.Contains(msg, i) {
+			return true
+		}
+	}
+	return false
+}
+
+// progressLine shows the progress of long operations on stderr when it's a terminal
+type progressLine struct {
+	enabled bool
+	shown   bool
+}
+
+func newProgressLine() *progressLine {
+	return &progressLine{enabled: terminal.IsTerminal(int(os.Stderr.Fd()))}
+}
+
+func (p *progressLine) update(f string, a ...interface{}) {
+	if !p.enabled {
+		return
+	}
+	fmt.Fprintf(os.Stderr, "\r\033[K"+f, a...)
+	p.shown = true
+}
+
+// clear removes the progress line before printing other output
+func (p *progressLine) clear() {
+	if p.shown {
+		fmt.Fprint(os.Stderr, "\r\033[K")
+		p.shown = false
+	}
+}
+
+// scanLogs runs the query over [start, end] in chunks, calling fn with the logs of each chunk as
+// they arrive. it returns the first block that wasn't scanned. the progress is shown when the
+// range takes more than one query
+func scanLogs(ctx context.Context, cl backend, query ethereum.FilterQuery, start, end uint64, fn func(types.Log) error) (uint64, error) {
+	progress := newProgressLine()
+	progress.enabled = progress.enabled && end-start >= logChunkBlocks
+	defer progress.clear()
+	// ceiling is the smallest range the provider refused
+	chunk, ceiling := uint64(logChunkBlocks), uint64(logChunkMaxBlocks)+1
+	var found int
+	from := start
+	for from <= end {
+		to := from + chunk - 1
+		if to > end || to < from {
+			to = end
+		}
+		progress.update("scanning blocks %d-%d of %d-%d (%d%%), %d found", from, to, start, end, (from-start)*100/(end-start+1), found)
+		query.FromBlock, query.ToBlock = new(big.Int).SetUint64(from), new(big.Int).SetUint64(to)
+		logs, err := cl.FilterLogs(ctx, query)
+		if ctx.Err() != nil {
+			return from, ctx.Err()
+		}
+		if err != nil {
+			if !isRangeLimitError(err) || chunk == 1 {
+				return from, err
+			}
+			ceiling, chunk = chunk, chunk/2
+			continue
+		}
+		progress.clear()
+		for _, l := range logs {
+			if err = fn(l); err != nil {
+				return from, err
+			}
+		}
+		found += len(logs)
+		if to == end {
+			return end + 1, nil
+		}
+		from = to + 1
+		if chunk*2 < ceiling {
+			chunk *= 2
+		}
+	}
+	return from, nil
+}
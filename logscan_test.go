@@ -0,0 +1,37 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+type testRPCError struct {
+	code int
+	msg  string
+}
+
+func (e *testRPCError) Error() string  { return e.msg }
+func (e *testRPCError) ErrorCode() int { return e.code }
+
+func TestIsRangeLimitError(t *testing.T) {
+	for _, i := range []struct {
+		err   error
+		limit bool
+	}{
+		{errors.New("query returned more than 10000 results"), true},
+		{errors.New("exceed maximum block range: 5000"), true},
+		{errors.New("Log response size exceeded. You can make eth_getLogs requests with up to a 2K block range"), true},
+		{errors.New("block range is too wide"), true},
+		{errors.New("eth_getLogs is limited to a 10,000 range"), true},
+		{fmt.Errorf("wrapped: %w", &testRPCError{code: -32005, msg: "limit exceeded"}), true},
+		{&testRPCError{code: -32000, msg: "header not found"}, false},
+		{errors.New("invalid block range params"), false},
+		{errors.New("insufficient funds: balance is not more than the cost"), false},
+		{errors.New("too many open files"), false},
+	} {
+		if r := isRangeLimitError(i.err); r != i.limit {
+			t.Errorf("%s: expecting %v, got %v", i.err, i.limit, r)
+		}
+	}
+}
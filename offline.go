@@ -0,0 +1,372 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"path/filepath"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/spf13/cobra"
+)
+
+var (
+	errUnsignedSaved = errors.New("unsigned transaction saved")
+	errWrongSigner   = errors.New("the transaction isn't from the signer")
+	errNotSigned     = errors.New("transaction isn't signed")
+)
+
+// unsignedTransaction is the file written by "build unsigned". the unsigned field is the
+// transaction that gets signed, the other fields describe it
+type unsignedTransaction struct {
+	ChainID     string        `json:"chainId"`
+	From        string        `json:"from"`
+	To          string        `json:"to,omitempty"`
+	Contract    string        `json:"contract"`
+	Method      string        `json:"method"`
+	Args        []valueOutput `json:"args"`
+	Nonce       uint64        `json:"nonce"`
+	Gas         uint64        `json:"gas"`
+	GasPrice    string        `json:"gasPrice,omitempty"`
+	MaxFee      string        `json:"maxFeePerGas,omitempty"`
+	PriorityFee string        `json:"maxPriorityFeePerGas,omitempty"`
+	Value       string        `json:"value"`
+	Data        string        `json:"data"`
+	Unsigned    string        `json:"unsigned"`
+}
+
+// newUnsignedTransactor sets only the sender, the transaction is built and signed elsewhere
+func newUnsignedTransactor(cl backend, from common.Address) (*bind.TransactOpts, error) {
+	chainID, err := cl.ChainID(context.Background())
+	if err != nil {
+		return nil, err
+	}
+	if err = sess.checkChainID(chainID); err != nil {
+		return nil, err
+	}
+	return &bind.TransactOpts{From: from, Context: context.Background()}, nil
+}
+
+// buildTransaction builds the transaction prepared in opts. legacy transactions are used
+// when there's a gas price
+func buildTransaction(chainID *big.Int, opts *bind.TransactOpts, to *common.Address, data []byte) *types.Transaction {
+	if opts.GasPrice != nil {
+		return types.NewTx(&types.LegacyTx{
+			Nonce:    opts.Nonce.Uint64(),
+			GasPrice: opts.GasPrice,
+			Gas:      opts.GasLimit,
+			To:       to,
+			Value:    opts.Value,
+			Data:     data,
+		})
+	}
+	return types.NewTx(&types.DynamicFeeTx{
+		ChainID:   chainID,
+		Nonce:     opts.Nonce.Uint64(),
+		GasTipCap: opts.GasTipCap,
+		GasFeeCap: opts.GasFeeCap,
+		Gas:       opts.GasLimit,
+		To:        to,
+		Value:     opts.Value,
+		Data:      data,
+	})
+}
+
+// writeUnsignedTransaction saves the prepared transaction for signing offline
+func writeUnsignedTransaction(cl backend, opts *bind.TransactOpts, req *txRequest, fn string) error {
+	chainID, err := cl.ChainID(context.Background())
+	if err != nil {
+		return err
+	}
+	tx := buildTransaction(chainID, opts, req.to, req.data)
+	raw, err := tx.MarshalBinary()
+	if err != nil {
+		return err
+	}
+	r := &unsignedTransaction{
+		ChainID:  chainID.String(),
+		From:     opts.From.Hex(),
+		Contract: req.contract,
+		Method:   req.method.Sig,
+		Args:     make([]valueOutput, 0, len(req.args)),
+		Nonce:    tx.Nonce(),
+		Gas:      tx.Gas(),
+		Value:    tx.Value().String(),
+		Data:     hexutil.Encode(req.data),
+		Unsigned: hexutil.Encode(raw),
+	}
+	if req.to != nil {
+		r.To = req.to.Hex()
+	} else {
+		r.Method = "constructor" + req.method.Sig
+	}
+	for n, i := range req.method.Inputs {
+		r.Args = append(r.Args, valueOutput{Name: argumentName(n, i), Type: i.Type.String(), Value: jsonValue(i.Type, req.args[n])})
+	}
+	if tx.Type() == types.LegacyTxType {
+		r.GasPrice = tx.GasPrice().String()
+	} else {
+		r.MaxFee, r.PriorityFee = tx.GasFeeCap().String(), tx.GasTipCap().String()
+	}
+	b, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err = ioutil.WriteFile(fn, append(b, '\n'), 0600); err != nil {
+		return err
+	}
+	return fmt.Errorf("%w to %s", errUnsignedSaved, fn)
+}
+
+func readUnsignedTransaction(fn string) (*unsignedTransaction, *types.Transaction, error) {
+	b, err := ioutil.ReadFile(fn)
+	if err != nil {
+		return nil, nil, err
+	}
+	r := &unsignedTransaction{}
+	if err = json.Unmarshal(b, r); err != nil {
+		return nil, nil, err
+	}
+	raw, err := hexutil.Decode(r.Unsigned)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid unsigned transaction: %w", err)
+	}
+	tx := &types.Transaction{}
+	if err = tx.UnmarshalBinary(raw); err != nil {
+		return nil, nil, fmt.Errorf("invalid unsigned transaction: %w", err)
+	}
+	return r, tx, nil
+}
+
+// readRawTransaction decodes a signed transaction given as hex or as a file holding the hex
+func readRawTransaction(s string) (*types.Transaction, error) {
+	s = strings.TrimSpace(s)
+	if !isHex(s) {
+		b, err := ioutil.ReadFile(s)
+		if err != nil {
+			return nil, err
+		}
+		s = strings.TrimSpace(string(b))
+	}
+	raw, err := parseHex(s)
+	if err != nil {
+		return nil, fmt.Errorf("invalid raw transaction: %w", err)
+	}
+	tx := &types.Transaction{}
+	if err = tx.UnmarshalBinary(raw); err != nil {
+		return nil, fmt.Errorf("invalid raw transaction: %w", err)
+	}
+	return tx, nil
+}
+
+// isHex reports whether s is hex, with or without the 0x prefix
+func isHex(s string) bool {
+	if strings.HasPrefix(s, "0x") || strings.HasPrefix(s, "0X") {
+		return true
+	}
+	if s == "" {
+		return false
+	}
+	for _, i := range s {
+		if !strings.ContainsRune("0123456789abcdefABCDEF", i) {
+			return false
+		}
+	}
+	return true
+}
+
+// transactionSender recovers the sender of a signed transaction
+func transactionSender(tx *types.Transaction) (common.Address, error) {
+	var chainID *big.Int
+	if tx.Protected() {
+		chainID = tx.ChainId()
+	}
+	return types.Sender(types.LatestSignerForChainID(chainID), tx)
+}
+
+// offlineABI is the abi used to decode the calldata of the transaction files
+func offlineABI() *abi.ABI {
+	if sess.sessionContract != nil {
+		return sess.abi
+	}
+	if rootFlags.abiFile == "" {
+		return nil
+	}
+	a, err := loadArtifact(rootFlags.abiFile, rootFlags.contract, false)
+	if err != nil {
+		infof("can't read abi: %s\n", err)
+		return nil
+	}
+	return a.abi
+}
+
+// formatRawTransaction describes a transaction from its own fields, decoding the calldata
+// with the abi when possible
+func formatRawTransaction(tx *types.Transaction, chainID *big.Int, from *common.Address, contractABI *abi.ABI) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "transaction:\n")
+	fmt.Fprintf(&b, "  chain: %s\n", chainDescription(chainID))
+	if from != nil {
+		fmt.Fprintf(&b, "  from: %s\n", from.Hex())
+	}
+	if tx.To() == nil {
+		fmt.Fprintf(&b, "  to: new contract\n")
+		fmt.Fprintf(&b, "  calldata: %d bytes of init code\n", len(tx.Data()))
+	} else {
+		fmt.Fprintf(&b, "  to: %s\n", tx.To().Hex())
+		formatCalldata(&b, tx.Data(), contractABI)
+	}
+	fmt.Fprintf(&b, "  value: %s ether\n", formatEther(tx.Value()))
+	fmt.Fprintf(&b, "  nonce: %d\n", tx.Nonce())
+	fmt.Fprintf(&b, "  gas limit: %d\n", tx.Gas())
+	if tx.Type() == types.DynamicFeeTxType {
+		fmt.Fprintf(&b, "  max priority fee: %s gwei\n", formatGwei(tx.GasTipCap()))
+		fmt.Fprintf(&b, "  max fee: %s gwei\n", formatGwei(tx.GasFeeCap()))
+	} else {
+		fmt.Fprintf(&b, "  gas price: %s gwei\n", formatGwei(tx.GasPrice()))
+	}
+	fmt.Fprintf(&b, "  max cost: %s ether\n", formatEther(tx.Cost()))
+	return b.String()
+}
+
+func formatCalldata(b *strings.Builder, data []byte, contractABI *abi.ABI) {
+	if contractABI != nil && len(data) >= 4 {
+		if m, err := contractABI.MethodById(data[:4]); err == nil {
+			if args, err := m.Inputs.Unpack(data[4:]); err == nil {
+				fmt.Fprintf(b, "  method: %s\n", m.Sig)
+				for n, i := range m.Inputs {
+					fmt.Fprintf(b, "    %s (%s): %v\n", argumentName(n, i), i.Type.String(), formatValue(args[n]))
+				}
+			}
+		}
+	}
+	fmt.Fprintf(b, "  calldata: %s\n", hexutil.Encode(data))
+}
+
+func runSignFile(cmd *cobra.Command, args []string) error {
+	if txSigner == nil {
+		return errNoSigner
+	}
+	var fn string
+	if len(args) > 0 {
+		fn = args[0]
+	} else {
+		p, err := filepath.Abs(".")
+		if err != nil {
+			return err
+		}
+		if fn, err = inputFilename("unsigned transaction file: ", p, true); err != nil {
+			return err
+		}
+	}
+	desc, tx, err := readUnsignedTransaction(fn)
+	if err != nil {
+		return err
+	}
+	chainID, ok := new(big.Int).SetString(desc.ChainID, 10)
+	if !ok {
+		return fmt.Errorf("invalid chain id: %s", desc.ChainID)
+	}
+	if tx.Type() != types.LegacyTxType && tx.ChainId().Cmp(chainID) != 0 {
+		return fmt.Errorf("%w: the transaction is for %s", errChainMismatch, chainDescription(tx.ChainId()))
+	}
+	from := txSigner.address()
+	if !common.IsHexAddress(desc.From) || common.HexToAddress(desc.From) != from {
+		return fmt.Errorf("%w: %s is from %s, the signer is %s", errWrongSigner, fn, desc.From, from.Hex())
+	}
+	infof("%s", formatRawTransaction(tx, chainID, &from, offlineABI()))
+	out, err := cmd.Flags().GetString("out")
+	if err != nil {
+		return err
+	}
+	if out == "" {
+		out = strings.TrimSuffix(strings.TrimSuffix(fn, filepath.Ext(fn)), "-unsigned") + ".signed"
+	}
+	if interactive {
+		sign, ok := inputYesNo("sign transaction? (%s): ", false)
+		if !ok || !sign {
+			return errAborted
+		}
+		if out, ok = inputTextWithDefault("signed transaction file (%s): ", out); !ok {
+			return errAborted
+		}
+	}
+	opts, err := txSigner.transactor(chainID)
+	if err != nil {
+		return err
+	}
+	signed, err := opts.Signer(from, tx)
+	if err != nil {
+		return fmt.Errorf("can't sign transaction: %w", err)
+	}
+	raw, err := signed.MarshalBinary()
+	if err != nil {
+		return err
+	}
+	if err = ioutil.WriteFile(out, []byte(hexutil.Encode(raw)+"\n"), 0600); err != nil {
+		return err
+	}
+	infof("transaction %s signed and saved to %s\n", signed.Hash().Hex(), out)
+	return nil
+}
+
+func runBroadcast(cmd *cobra.Command, args []string) error {
+	var s string
+	if len(args) > 0 {
+		s = args[0]
+	} else {
+		s = inputText("signed transaction file or hex: ")
+	}
+	tx, err := readRawTransaction(s)
+	if err != nil {
+		return err
+	}
+	from, err := transactionSender(tx)
+	if err != nil {
+		return fmt.Errorf("%w: %s", errNotSigned, err)
+	}
+	if tx.Protected() {
+		if err = sess.checkChainID(tx.ChainId()); err != nil {
+			return err
+		}
+	}
+	contractABI := offlineABI()
+	infof("%s", formatRawTransaction(tx, sess.chainID, &from, contractABI))
+	if interactive {
+		send, ok := inputYesNo("broadcast transaction? (%s): ", false)
+		if !ok || !send {
+			return errAborted
+		}
+	}
+	if err = sess.client.SendTransaction(context.Background(), tx); err != nil {
+		return fmt.Errorf("can't broadcast transaction: %w", err)
+	}
+	addr, method := tx.To(), ""
+	if addr == nil {
+		a := crypto.CreateAddress(from, tx.Nonce())
+		addr, method = &a, "constructor"
+	} else if contractABI != nil && len(tx.Data()) >= 4 {
+		if m, err := contractABI.MethodById(tx.Data()[:4]); err == nil {
+			method = m.Name
+		}
+	}
+	var contract string
+	if sess.sessionContract != nil && *addr == sess.address {
+		contract = sess.name
+	}
+	sess.recordTransaction(from, contract, method, contractABI, tx)
+	if contractABI == nil {
+		contractABI = &abi.ABI{}
+	}
+	showTransaction(sess.client, addr, contractABI, method, tx)
+	return nil
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// signedTestTransaction returns a signed contract call with 400 bytes of calldata
+func signedTestTransaction(t *testing.T) (*types.Transaction, string) {
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	to := common.HexToAddress("0x1111111111111111111111111111111111111111")
+	tx, err := types.SignNewTx(key, types.LatestSignerForChainID(big.NewInt(1337)), &types.DynamicFeeTx{
+		ChainID:   big.NewInt(1337),
+		GasTipCap: big.NewInt(1),
+		GasFeeCap: big.NewInt(2e9),
+		Gas:       100000,
+		To:        &to,
+		Data:      []byte(strings.Repeat("\xab", 400)),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw, err := tx.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tx, hexutil.Encode(raw)
+}
+
+func TestReadRawTransactionHex(t *testing.T) {
+	tx, s := signedTestTransaction(t)
+	for _, i := range []string{s, strings.TrimPrefix(s, "0x"), " " + s + "\n"} {
+		r, err := readRawTransaction(i)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if r.Hash() != tx.Hash() {
+			t.Errorf("expecting transaction %s, got %s", tx.Hash().Hex(), r.Hash().Hex())
+		}
+	}
+	if _, err := readRawTransaction(s[:len(s)-1]); err == nil {
+		t.Error("invalid hex decoded")
+	}
+}
+
+func TestReadRawTransactionFile(t *testing.T) {
+	tx, s := signedTestTransaction(t)
+	dir, err := ioutil.TempDir("", "scui")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	fn := filepath.Join(dir, "tx.signed")
+	if err = ioutil.WriteFile(fn, []byte(s+"\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	r, err := readRawTransaction(fn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Hash() != tx.Hash() {
+		t.Errorf("expecting transaction %s, got %s", tx.Hash().Hex(), r.Hash().Hex())
+	}
+	if _, err = readRawTransaction(filepath.Join(dir, "missing.signed")); !os.IsNotExist(err) {
+		t.Errorf("expecting a missing file error, got %v", err)
+	}
+}
@@ -0,0 +1,299 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+const (
+	outputText  = "text"
+	outputJSON  = "json"
+	outputJSONL = "jsonl"
+)
+
+var outputFormats = []string{outputText, outputJSON, outputJSONL}
+
+var outputFormat = outputText
+
+// outputFormatValue is a flag value accepting the output formats
+type outputFormatValue struct{ v *string }
+
+func (o outputFormatValue) String() string { return *o.v }
+func (o outputFormatValue) Type() string   { return "format" }
+
+func (o outputFormatValue) Set(s string) error {
+	for _, i := range outputFormats {
+		if s == i {
+			*o.v = s
+			return nil
+		}
+	}
+	return fmt.Errorf("invalid output format (expecting %s)", strings.Join(outputFormats, ", "))
+}
+
+// infof prints informative messages, moving them out of the way when the output is machine readable
+func infof(f string, a ...interface{}) {
+	if outputFormat == outputText {
+		fmt.Printf(f, a...)
+	} else {
+		fmt.Fprintf(os.Stderr, f, a...)
+	}
+}
+
+func marshalOutput(v interface{}, prefix string) ([]byte, error) {
+	if outputFormat == outputJSON {
+		return json.MarshalIndent(v, prefix, "  ")
+	}
+	return json.Marshal(v)
+}
+
+func printJSON(v interface{}) {
+	b, err := marshalOutput(v, "")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "can't marshal output: %s\n", err)
+		return
+	}
+	fmt.Println(string(b))
+}
+
+// jsonValue converts an abi value to a json friendly value
+func jsonValue(t abi.Type, v interface{}) interface{} {
+	// indexed dynamic values are only available as hashes
+	if h, ok := v.(common.Hash); ok {
+		return h.Hex()
+	}
+	switch t.T {
+	case abi.IntTy, abi.UintTy:
+		if t.Size <= 32 {
+			return v
+		}
+		return fmt.Sprint(v)
+	case abi.AddressTy:
+		return v.(common.Address).Hex()
+	case abi.BytesTy, abi.FixedBytesTy, abi.HashTy, abi.FunctionTy:
+		return formatValue(v)
+	case abi.SliceTy, abi.ArrayTy:
+		rv := reflect.ValueOf(v)
+		r := make([]interface{}, 0, rv.Len())
+		for i := 0; i < rv.Len(); i++ {
+			r = append(r, jsonValue(*t.Elem, rv.Index(i).Interface()))
+		}
+		return r
+	case abi.TupleTy:
+		rv := reflect.Indirect(reflect.ValueOf(v))
+		r := make(map[string]interface{}, len(t.TupleElems))
+		for n, i := range t.TupleElems {
+			name := t.TupleRawNames[n]
+			if name == "" {
+				name = fmt.Sprintf("arg%d", n)
+			}
+			r[name] = jsonValue(*i, rv.Field(n).Interface())
+		}
+		return r
+	}
+	return v
+}
+
+type valueOutput struct {
+	Name  string      `json:"name"`
+	Type  string      `json:"type"`
+	Value interface{} `json:"value"`
+}
+
+type callOutput struct {
+	Method  string        `json:"method"`
+	Outputs []valueOutput `json:"outputs"`
+}
+
+func newCallOutput(method *abi.Method, values []interface{}) *callOutput {
+	r := &callOutput{Method: method.Name, Outputs: make([]valueOutput, 0, len(values))}
+	for n, i := range values {
+		arg := method.Outputs[n]
+		r.Outputs = append(r.Outputs, valueOutput{
+			Name:  arg.Name,
+			Type:  arg.Type.String(),
+			Value: jsonValue(arg.Type, i),
+		})
+	}
+	return r
+}
+
+type eventOutput struct {
+	Event           string                 `json:"event"`
+	Address         string                 `json:"address"`
+	BlockNumber     uint64                 `json:"blockNumber"`
+	TransactionHash string                 `json:"transactionHash"`
+	LogIndex        uint                   `json:"logIndex"`
+	Values          map[string]interface{} `json:"values"`
+	// Status is the confirmation status of watched events
+	Status string `json:"status,omitempty"`
+}
+
+func newEventOutput(ev *abi.Event, eventData map[string]interface{}, l *types.Log) *eventOutput {
+	r := &eventOutput{
+		Event:           ev.Name,
+		Address:         l.Address.Hex(),
+		BlockNumber:     l.BlockNumber,
+		TransactionHash: l.TxHash.Hex(),
+		LogIndex:        l.Index,
+		Values:          make(map[string]interface{}, len(ev.Inputs)),
+	}
+	for n, i := range ev.Inputs {
+		r.Values[argumentName(n, i)] = jsonValue(i.Type, eventData[i.Name])
+	}
+	return r
+}
+
+type transactionOutput struct {
+	Method            string         `json:"method"`
+	TransactionHash   string         `json:"transactionHash"`
+	Status            string         `json:"status"`
+	ContractAddress   string         `json:"contractAddress,omitempty"`
+	BlockNumber       *uint64        `json:"blockNumber,omitempty"`
+	GasUsed           *uint64        `json:"gasUsed,omitempty"`
+	EffectiveGasPrice string         `json:"effectiveGasPrice,omitempty"`
+	Events            []*eventOutput `json:"events,omitempty"`
+	RevertReason      string         `json:"revertReason,omitempty"`
+}
+
+func newTransactionOutput(addr *common.Address, contractABI *abi.ABI, method string, tx *types.Transaction, mt *minedTransaction) *transactionOutput {
+	r := &transactionOutput{
+		Method:          method,
+		TransactionHash: tx.Hash().Hex(),
+		Status:          mt.status(),
+	}
+	if mt == nil {
+		return r
+	}
+	blockNumber := mt.receipt.BlockNumber.Uint64()
+	r.BlockNumber = &blockNumber
+	r.GasUsed = &mt.receipt.GasUsed
+	if mt.gasPrice != nil {
+		r.EffectiveGasPrice = mt.gasPrice.String()
+	}
+	r.RevertReason = mt.revertReason
+	for _, l := range contractLogs(addr, mt.receipt) {
+		ev, eventData, err := decodeLog(contractABI, l)
+		if err != nil {
+			continue
+		}
+		r.Events = append(r.Events, newEventOutput(ev, eventData, l))
+	}
+	return r
+}
+
+// logOutput is a log that doesn't match the events of the abi
+type logOutput struct {
+	Address         string   `json:"address"`
+	BlockNumber     uint64   `json:"blockNumber"`
+	TransactionHash string   `json:"transactionHash"`
+	LogIndex        uint     `json:"logIndex"`
+	Topics          []string `json:"topics"`
+	Data            string   `json:"data"`
+	Status          string   `json:"status,omitempty"`
+}
+
+func newLogOutput(l *types.Log) *logOutput {
+	r := &logOutput{
+		Address:         l.Address.Hex(),
+		BlockNumber:     l.BlockNumber,
+		TransactionHash: l.TxHash.Hex(),
+		LogIndex:        l.Index,
+		Topics:          make([]string, 0, len(l.Topics)),
+		Data:            hexutil.Encode(l.Data),
+	}
+	for _, i := range l.Topics {
+		r.Topics = append(r.Topics, i.Hex())
+	}
+	return r
+}
+
+// eventPrinter outputs events in the selected format. lists are streamed as a json array. names
+// adds the event names to the text output when several events are printed
+type eventPrinter struct {
+	list  bool
+	names bool
+	count int
+}
+
+func (p *eventPrinter) print(ev *abi.Event, eventData map[string]interface{}, l *types.Log) {
+	p.printStatus(ev, eventData, l, "")
+}
+
+// printStatus prints the event marked with its confirmation status
+func (p *eventPrinter) printStatus(ev *abi.Event, eventData map[string]interface{}, l *types.Log, status string) {
+	if outputFormat != outputText {
+		r := newEventOutput(ev, eventData, l)
+		r.Status = status
+		p.printJSON(r)
+		return
+	}
+	values := formatEventValues(ev.Inputs, eventData)
+	if p.names {
+		values = strings.TrimSpace(ev.Name + " " + values)
+	}
+	p.printText(l, status, values)
+}
+
+// printRaw prints a log that can't be decoded
+func (p *eventPrinter) printRaw(l *types.Log, status string) {
+	if outputFormat != outputText {
+		r := newLogOutput(l)
+		r.Status = status
+		p.printJSON(r)
+		return
+	}
+	topics := make([]string, 0, len(l.Topics))
+	for _, i := range l.Topics {
+		topics = append(topics, i.Hex())
+	}
+	p.printText(l, status, fmt.Sprintf("unknown log %d topics=[%s] data=%s", l.Index, strings.Join(topics, " "), hexutil.Encode(l.Data)))
+}
+
+func (p *eventPrinter) printText(l *types.Log, status, s string) {
+	if status != "" {
+		fmt.Printf("  block %d (%s): %s\n", l.BlockNumber, status, s)
+	} else {
+		fmt.Printf("  block %d: %s\n", l.BlockNumber, s)
+	}
+	p.count++
+}
+
+func (p *eventPrinter) printJSON(v interface{}) {
+	if outputFormat != outputJSON || !p.list {
+		printJSON(v)
+		p.count++
+		return
+	}
+	b, err := marshalOutput(v, "  ")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "can't marshal output: %s\n", err)
+		return
+	}
+	if p.count == 0 {
+		fmt.Print("[\n  ")
+	} else {
+		fmt.Print(",\n  ")
+	}
+	fmt.Print(string(b))
+	p.count++
+}
+
+func (p *eventPrinter) close() {
+	if outputFormat != outputJSON || !p.list {
+		return
+	}
+	if p.count == 0 {
+		fmt.Println("[]")
+	} else {
+		fmt.Println("\n]")
+	}
+}
@@ -0,0 +1,309 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"io"
+)
+
+// a minimal parquet writer: required flat columns, plain encoding, no compression, one data
+// page per column chunk. the format is described in https://github.com/apache/parquet-format
+
+// parquet physical types
+const (
+	parquetBoolean   = 0
+	parquetInt64     = 2
+	parquetByteArray = 6
+)
+
+// parquet converted types
+const (
+	parquetUTF8            = 0
+	parquetTimestampMillis = 9
+	parquetUint64          = 14
+	parquetJSON            = 19
+)
+
+// rows buffered before a row group is written
+const parquetRowGroupRows = 100000
+
+var parquetMagic = []byte("PAR1")
+
+type parquetColumn struct {
+	name      string
+	typ       int32
+	converted int32 // -1 without converted type
+	data      bytes.Buffer
+	bools     []bool
+}
+
+func (c *parquetColumn) appendInt64(v int64) {
+	var b [8]byte
+	binary.LittleEndian.PutUint64(b[:], uint64(v))
+	c.data.Write(b[:])
+}
+
+func (c *parquetColumn) appendBytes(v []byte) {
+	var b [4]byte
+	binary.LittleEndian.PutUint32(b[:], uint32(len(v)))
+	c.data.Write(b[:])
+	c.data.Write(v)
+}
+
+func (c *parquetColumn) appendBool(v bool) { c.bools = append(c.bools, v) }
+
+// page returns the plain encoded values
+func (c *parquetColumn) page() []byte {
+	if c.typ != parquetBoolean {
+		return c.data.Bytes()
+	}
+	r := make([]byte, (len(c.bools)+7)/8)
+	for n, i := range c.bools {
+		if i {
+			r[n/8] |= 1 << (n % 8)
+		}
+	}
+	return r
+}
+
+func (c *parquetColumn) reset() {
+	c.data.Reset()
+	c.bools = c.bools[:0]
+}
+
+type parquetChunk struct {
+	offset int64
+	size   int64
+}
+
+type parquetRowGroup struct {
+	chunks []parquetChunk
+	rows   int64
+}
+
+// parquetWriter writes rows to a parquet file. values are appended to the columns, then the row is
+// ended with endRow
+type parquetWriter struct {
+	w         *bufio.Writer
+	c         io.Closer
+	offset    int64
+	columns   []*parquetColumn
+	rows      int64
+	rowGroups []parquetRowGroup
+	// rows of each row group
+	groupRows int64
+}
+
+func newParquetWriter(w io.WriteCloser, columns []*parquetColumn) (*parquetWriter, error) {
+	r := &parquetWriter{w: bufio.NewWriter(w), c: w, columns: columns, groupRows: parquetRowGroupRows}
+	if err := r.write(parquetMagic); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (p *parquetWriter) write(b []byte) error {
+	n, err := p.w.Write(b)
+	p.offset += int64(n)
+	return err
+}
+
+func (p *parquetWriter) endRow() error {
+	p.rows++
+	if p.rows%p.groupRows == 0 {
+		return p.flushRowGroup()
+	}
+	return nil
+}
+
+func (p *parquetWriter) flushRowGroup() error {
+	rows := p.rows
+	for _, i := range p.rowGroups {
+		rows -= i.rows
+	}
+	if rows == 0 {
+		return nil
+	}
+	rg := parquetRowGroup{rows: rows}
+	for _, i := range p.columns {
+		page := i.page()
+		t := newThriftWriter()
+		t.i32(1, 0) // data page
+		t.i32(2, int32(len(page)))
+		t.i32(3, int32(len(page)))
+		t.beginStruct(5)
+		t.i32(1, int32(rows))
+		t.i32(2, 0) // plain
+		t.i32(3, 3) // rle
+		t.i32(4, 3)
+		t.endStruct()
+		t.stop()
+		chunk := parquetChunk{offset: p.offset, size: int64(t.buf.Len() + len(page))}
+		if err := p.write(t.buf.Bytes()); err != nil {
+			return err
+		}
+		if err := p.write(page); err != nil {
+			return err
+		}
+		rg.chunks = append(rg.chunks, chunk)
+		i.reset()
+	}
+	p.rowGroups = append(p.rowGroups, rg)
+	return nil
+}
+
+// close writes the pending rows and the file metadata
+func (p *parquetWriter) close() error {
+	if err := p.flushRowGroup(); err != nil {
+		p.c.Close()
+		return err
+	}
+	t := newThriftWriter()
+	t.i32(1, 1)
+	t.beginList(2, thriftStruct, len(p.columns)+1)
+	t.beginElement()
+	t.binary(4, []byte("schema"))
+	t.i32(5, int32(len(p.columns)))
+	t.endElement()
+	for _, i := range p.columns {
+		t.beginElement()
+		t.i32(1, i.typ)
+		t.i32(3, 0) // required
+		t.binary(4, []byte(i.name))
+		if i.converted >= 0 {
+			t.i32(6, i.converted)
+		}
+		t.endElement()
+	}
+	t.i64(3, p.rows)
+	t.beginList(4, thriftStruct, len(p.rowGroups))
+	for _, rg := range p.rowGroups {
+		t.beginElement()
+		t.beginList(1, thriftStruct, len(rg.chunks))
+		var size int64
+		for n, i := range rg.chunks {
+			col := p.columns[n]
+			size += i.size
+			t.beginElement()
+			t.i64(2, i.offset)
+			t.beginStruct(3)
+			t.i32(1, col.typ)
+			t.beginList(2, thriftI32, 2)
+			t.listI32(0)
+			t.listI32(3)
+			t.beginList(3, thriftBinary, 1)
+			t.listBinary([]byte(col.name))
+			t.i32(4, 0) // uncompressed
+			t.i64(5, rg.rows)
+			t.i64(6, i.size)
+			t.i64(7, i.size)
+			t.i64(9, i.offset)
+			t.endStruct()
+			t.endElement()
+		}
+		t.i64(2, size)
+		t.i64(3, rg.rows)
+		t.endElement()
+	}
+	t.binary(6, []byte("scui"))
+	t.stop()
+	var size [4]byte
+	binary.LittleEndian.PutUint32(size[:], uint32(t.buf.Len()))
+	for _, i := range [][]byte{t.buf.Bytes(), size[:], parquetMagic} {
+		if err := p.write(i); err != nil {
+			p.c.Close()
+			return err
+		}
+	}
+	if err := p.w.Flush(); err != nil {
+		p.c.Close()
+		return err
+	}
+	return p.c.Close()
+}
+
+// thrift compact protocol types
+const (
+	thriftI32    = 5
+	thriftI64    = 6
+	thriftBinary = 8
+	thriftList   = 9
+	thriftStruct = 12
+)
+
+// thriftWriter encodes structs with the thrift compact protocol
+type thriftWriter struct {
+	buf bytes.Buffer
+	// last field id of each open struct
+	last []int16
+}
+
+func newThriftWriter() *thriftWriter { return &thriftWriter{last: []int16{0}} }
+
+func (t *thriftWriter) uvarint(v uint64) {
+	var b [binary.MaxVarintLen64]byte
+	t.buf.Write(b[:binary.PutUvarint(b[:], v)])
+}
+
+func (t *thriftWriter) varint(v int64) { t.uvarint(uint64((v << 1) ^ (v >> 63))) }
+
+func (t *thriftWriter) field(id int16, typ byte) {
+	n := len(t.last) - 1
+	if delta := id - t.last[n]; delta > 0 && delta <= 15 {
+		t.buf.WriteByte(byte(delta)<<4 | typ)
+	} else {
+		t.buf.WriteByte(typ)
+		t.varint(int64(id))
+	}
+	t.last[n] = id
+}
+
+func (t *thriftWriter) i32(id int16, v int32) {
+	t.field(id, thriftI32)
+	t.varint(int64(v))
+}
+
+func (t *thriftWriter) i64(id int16, v int64) {
+	t.field(id, thriftI64)
+	t.varint(v)
+}
+
+func (t *thriftWriter) binary(id int16, v []byte) {
+	t.field(id, thriftBinary)
+	t.listBinary(v)
+}
+
+func (t *thriftWriter) beginStruct(id int16) {
+	t.field(id, thriftStruct)
+	t.beginElement()
+}
+
+func (t *thriftWriter) endStruct() { t.endElement() }
+
+func (t *thriftWriter) beginList(id int16, elemType byte, size int) {
+	t.field(id, thriftList)
+	if size < 15 {
+		t.buf.WriteByte(byte(size)<<4 | elemType)
+		return
+	}
+	t.buf.WriteByte(0xf0 | elemType)
+	t.uvarint(uint64(size))
+}
+
+// beginElement starts a struct inside a list
+func (t *thriftWriter) beginElement() { t.last = append(t.last, 0) }
+
+func (t *thriftWriter) endElement() {
+	t.stop()
+	t.last = t.last[:len(t.last)-1]
+}
+
+func (t *thriftWriter) listI32(v int32) { t.varint(int64(v)) }
+
+func (t *thriftWriter) listBinary(v []byte) {
+	t.uvarint(uint64(len(v)))
+	t.buf.Write(v)
+}
+
+func (t *thriftWriter) stop() { t.buf.WriteByte(0) }
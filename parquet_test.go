@@ -0,0 +1,103 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+)
+
+// the golden files were read back with an independent parquet reader when they were written
+var updateParquet = flag.Bool("update-parquet", false, "rewrite the parquet golden files")
+
+type bufferCloser struct{ bytes.Buffer }
+
+func (b *bufferCloser) Close() error { return nil }
+
+func testParquetColumns() []*parquetColumn {
+	return []*parquetColumn{
+		{name: "int", typ: parquetInt64, converted: -1},
+		{name: "uint", typ: parquetInt64, converted: parquetUint64},
+		{name: "bool", typ: parquetBoolean, converted: -1},
+		{name: "string", typ: parquetByteArray, converted: parquetUTF8},
+		{name: "json", typ: parquetByteArray, converted: parquetJSON},
+		{name: "timestamp", typ: parquetInt64, converted: parquetTimestampMillis},
+	}
+}
+
+// writeTestParquet writes the rows, one value per column, with groupRows rows per row group
+func writeTestParquet(t *testing.T, columns []*parquetColumn, groupRows int64, rows [][]interface{}) []byte {
+	var b bufferCloser
+	w, err := newParquetWriter(&b, columns)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.groupRows = groupRows
+	for _, row := range rows {
+		for n, i := range row {
+			c := w.columns[n]
+			switch v := i.(type) {
+			case int64:
+				c.appendInt64(v)
+			case uint64:
+				c.appendInt64(int64(v))
+			case bool:
+				c.appendBool(v)
+			case string:
+				c.appendBytes([]byte(v))
+			}
+		}
+		if err = w.endRow(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err = w.close(); err != nil {
+		t.Fatal(err)
+	}
+	return b.Bytes()
+}
+
+// checkParquetGolden compares b with the golden file, or rewrites it with -update-parquet
+func checkParquetGolden(t *testing.T, name string, b []byte) {
+	fn := filepath.Join("testdata", "parquet", name)
+	if *updateParquet {
+		if err := ioutil.WriteFile(fn, b, 0644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	expected, err := ioutil.ReadFile(fn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(b, expected) {
+		t.Errorf("%s: the file written differs from the golden file", name)
+	}
+}
+
+func TestParquetTypes(t *testing.T) {
+	b := writeTestParquet(t, testParquetColumns(), parquetRowGroupRows, [][]interface{}{
+		{int64(-1), uint64(1<<64 - 1), true, "0xabc", `{"a":1}`, int64(1700000000000)},
+		{int64(0), uint64(0), false, "", `[]`, int64(0)},
+		{int64(1 << 62), uint64(42), true, "ünïcode", `"s"`, int64(1700000012000)},
+	})
+	checkParquetGolden(t, "types.parquet", b)
+}
+
+func TestParquetRowGroups(t *testing.T) {
+	var rows [][]interface{}
+	for i := 0; i < 7; i++ {
+		rows = append(rows, []interface{}{int64(i), i%2 == 0})
+	}
+	// two full row groups and the remaining row written on close
+	b := writeTestParquet(t, []*parquetColumn{
+		{name: "n", typ: parquetInt64, converted: -1},
+		{name: "even", typ: parquetBoolean, converted: -1},
+	}, 3, rows)
+	checkParquetGolden(t, "rowgroups.parquet", b)
+}
+
+func TestParquetEmpty(t *testing.T) {
+	checkParquetGolden(t, "empty.parquet", writeTestParquet(t, testParquetColumns(), parquetRowGroupRows, nil))
+}
@@ -0,0 +1,189 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/c-bata/go-prompt"
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+var (
+	errDryRun       = errors.New("dry run, transaction not sent")
+	errNotConfirmed = errors.New("transaction not sent, use --yes to send it or --dry-run to only preview it")
+)
+
+// txRequest describes a transaction before it's signed
+type txRequest struct {
+	contract string
+	// to is nil for contract creations
+	to     *common.Address
+	method *abi.Method
+	args   []interface{}
+	data   []byte
+}
+
+// callSimulation is the result of running the transaction as a call at the pending block
+type callSimulation struct {
+	estimatedGas uint64
+	outputs      []interface{}
+	err          error
+}
+
+// prepareTransaction fills the fees, gas limit and nonce of a transaction. when confirm is set
+// the transaction preview is shown and sending it must be confirmed, in the console or with --yes
+func prepareTransaction(cl backend, contractABI *abi.ABI, opts *bind.TransactOpts, req *txRequest, params *transactParams, confirm bool) error {
+	opts.Value = params.value
+	fees, err := applyFees(cl, opts, params)
+	if err != nil {
+		return err
+	}
+	msg := ethereum.CallMsg{
+		From:      opts.From,
+		To:        req.to,
+		GasPrice:  opts.GasPrice,
+		GasFeeCap: opts.GasFeeCap,
+		GasTipCap: opts.GasTipCap,
+		Value:     opts.Value,
+		Data:      req.data,
+	}
+	// with a given gas limit the transaction can be sent even if the estimation fails
+	sim := &callSimulation{}
+	if sim.estimatedGas, err = cl.EstimateGas(context.Background(), msg); err != nil {
+		if params.gasLimit == 0 {
+			return explainTransactError(cl, contractABI, msg, err)
+		}
+		sim.err = explainTransactError(cl, contractABI, msg, err)
+	}
+	opts.GasLimit = params.gasLimit
+	if opts.GasLimit == 0 {
+		opts.GasLimit = sim.estimatedGas
+	}
+	if params.nonce == nil && sess.nextNonce != nil {
+		params.nonce = sess.nextNonce
+	}
+	if params.nonce != nil {
+		opts.Nonce = new(big.Int).SetUint64(*params.nonce)
+	} else {
+		nonce, err := cl.PendingNonceAt(context.Background(), opts.From)
+		if err != nil {
+			return fmt.Errorf("can't get nonce: %w", err)
+		}
+		opts.Nonce = new(big.Int).SetUint64(nonce)
+	}
+	if !confirm {
+		return nil
+	}
+	msg.Gas = opts.GasLimit
+	if out, err := cl.PendingCallContract(context.Background(), msg); err != nil {
+		sim.err = explainCallError(contractABI, err)
+	} else if req.to != nil && len(req.method.Outputs) > 0 {
+		sim.outputs, sim.err = req.method.Outputs.Unpack(out)
+	}
+	infof("%s", formatTxPreview(opts, req, fees, sim))
+	if params.dryRun {
+		return errDryRun
+	}
+	if params.unsignedFile != "" || params.yes {
+		return nil
+	}
+	if !interactive {
+		return errNotConfirmed
+	}
+	choices := []string{"yes", "no", "dry-run", "unsigned"}
+	if params.from != nil {
+		// without a signer the transaction can only be saved unsigned
+		choices = choices[1:]
+	}
+	for {
+		r, ok := inputMultiChoiceString("send transaction? ("+strings.Join(choices, ", ")+") (%s): ", "no", choices, func(c []prompt.Suggest) {
+			fmt.Printf("\nyes sends the transaction, dry-run keeps the simulation without sending, unsigned saves it to be signed offline\n")
+		})
+		switch {
+		case !ok || r == "no":
+			return errAborted
+		case r == "dry-run":
+			return errDryRun
+		case r == "yes" && params.from == nil:
+			return nil
+		case r == "unsigned":
+			return inputUnsignedTransaction(opts, req, params)
+		}
+	}
+}
+
+// inputUnsignedTransaction asks for the nonce and the file of the unsigned transaction
+func inputUnsignedTransaction(opts *bind.TransactOpts, req *txRequest, params *transactParams) error {
+	nonce, ok := inputIntWithDefault("nonce (%d): ", int(opts.Nonce.Int64()))
+	if !ok {
+		return errAborted
+	}
+	opts.Nonce = big.NewInt(int64(nonce))
+	name := req.contract + "-constructor"
+	if req.to != nil {
+		name = req.contract + "-" + req.method.Name
+	}
+	fn, ok := inputTextWithDefault("unsigned transaction file (%s): ", name+"-unsigned.json")
+	if !ok {
+		return errAborted
+	}
+	params.unsignedFile = fn
+	return nil
+}
+
+func formatTxPreview(opts *bind.TransactOpts, req *txRequest, fees *feeSuggestion, sim *callSimulation) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "transaction:\n")
+	fmt.Fprintf(&b, "  chain: %s\n", chainDescription(sess.chainID))
+	fmt.Fprintf(&b, "  from: %s\n", opts.From.Hex())
+	if req.to == nil {
+		fmt.Fprintf(&b, "  to: new %s contract\n", req.contract)
+	} else {
+		fmt.Fprintf(&b, "  to: %s (%s)\n", req.to.Hex(), req.contract)
+	}
+	if req.to == nil {
+		fmt.Fprintf(&b, "  method: constructor%s\n", req.method.Sig)
+	} else {
+		fmt.Fprintf(&b, "  method: %s\n", req.method.Sig)
+	}
+	for n, i := range req.method.Inputs {
+		fmt.Fprintf(&b, "    %s (%s): %v\n", argumentName(n, i), i.Type.String(), formatValue(req.args[n]))
+	}
+	if req.to == nil {
+		// the init code is too long to be useful
+		fmt.Fprintf(&b, "  calldata: %d bytes of init code\n", len(req.data))
+	} else {
+		fmt.Fprintf(&b, "  calldata: %s\n", hexutil.Encode(req.data))
+	}
+	fmt.Fprintf(&b, "  value: %s ether\n", formatEther(valueOrZero(opts.Value)))
+	fmt.Fprintf(&b, "  nonce: %s\n", opts.Nonce)
+	formatFees(&b, opts, fees)
+	fmt.Fprintf(&b, "  max cost: %s ether\n", formatEther(worstCaseCost(opts)))
+	fmt.Fprintf(&b, "simulation (pending block):\n")
+	if sim.estimatedGas != 0 {
+		fmt.Fprintf(&b, "  estimated gas: %d\n", sim.estimatedGas)
+	}
+	if sim.err != nil {
+		fmt.Fprintf(&b, "  result: %s\n", sim.err)
+	} else {
+		fmt.Fprintf(&b, "  result: success\n")
+		for n, i := range sim.outputs {
+			fmt.Fprintf(&b, "    (%s) %v\n", req.method.Outputs[n].Type.String(), formatValue(i))
+		}
+	}
+	return b.String()
+}
+
+func valueOrZero(v *big.Int) *big.Int {
+	if v == nil {
+		return new(big.Int)
+	}
+	return v
+}
@@ -0,0 +1,217 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+type receiptSettings struct {
+	wait          bool
+	confirmations int
+	timeout       time.Duration
+}
+
+var receiptConfig = receiptSettings{
+	wait:          true,
+	confirmations: 1,
+	timeout:       5 * time.Minute,
+}
+
+var (
+	errWaitInterrupted = errors.New("interrupted")
+	errWaitTimeout     = errors.New("timeout")
+)
+
+func waitReceipt(cl backend, tx *types.Transaction, confirmations int, timeout time.Duration) (*types.Receipt, error) {
+	ctx, cancel := interruptContext()
+	defer cancel()
+	waitCtx := ctx
+	if timeout > 0 {
+		var cancelTimeout context.CancelFunc
+		waitCtx, cancelTimeout = context.WithTimeout(ctx, timeout)
+		defer cancelTimeout()
+	}
+	r, err := waitConfirmed(waitCtx, cl, tx, confirmations)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, errWaitInterrupted
+		}
+		if waitCtx.Err() != nil {
+			return nil, errWaitTimeout
+		}
+		return nil, err
+	}
+	return r, nil
+}
+
+func waitConfirmed(ctx context.Context, cl backend, tx *types.Transaction, confirmations int) (*types.Receipt, error) {
+	r, err := bind.WaitMined(ctx, cl, tx)
+	if err != nil {
+		return nil, err
+	}
+	if confirmations <= 1 {
+		return r, nil
+	}
+	target := new(big.Int).Add(r.BlockNumber, big.NewInt(int64(confirmations-1)))
+	queryTicker := time.NewTicker(time.Second)
+	defer queryTicker.Stop()
+	for {
+		h, err := cl.HeaderByNumber(ctx, nil)
+		if err != nil {
+			return nil, err
+		}
+		if h.Number.Cmp(target) >= 0 {
+			// the transaction may have been moved to another block by a reorg
+			return bind.WaitMined(ctx, cl, tx)
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-queryTicker.C:
+		}
+	}
+}
+
+type minedTransaction struct {
+	tx           *types.Transaction
+	receipt      *types.Receipt
+	gasPrice     *big.Int
+	revertReason string
+}
+
+func (mt *minedTransaction) status() string {
+	if mt == nil {
+		return "pending"
+	}
+	if mt.receipt.Status == types.ReceiptStatusSuccessful {
+		return "success"
+	}
+	return "failed"
+}
+
+func waitMinedTransaction(cl backend, abi *abi.ABI, tx *types.Transaction) (*minedTransaction, error) {
+	r, err := waitReceipt(cl, tx, receiptConfig.confirmations, receiptConfig.timeout)
+	if err != nil {
+		return nil, err
+	}
+	mt := &minedTransaction{tx: tx, receipt: r}
+	if mt.gasPrice, err = effectiveGasPrice(cl, tx, r); err != nil {
+		infof("can't get effective gas price: %s\n", err)
+	}
+	if r.Status != types.ReceiptStatusSuccessful {
+		reason, err := minedRevertReason(cl, abi, tx, r)
+		if err != nil {
+			mt.revertReason = fmt.Sprintf("unknown (%s)", err)
+		} else {
+			mt.revertReason = reason
+		}
+	}
+	return mt, nil
+}
+
+// showTransaction outputs a sent transaction, waiting for the receipt if configured
+func showTransaction(cl backend, addr *common.Address, abi *abi.ABI, method string, tx *types.Transaction) {
+	infof("transaction sent: %s\n", tx.Hash().Hex())
+	var mt *minedTransaction
+	if receiptConfig.wait {
+		infof("waiting for receipt (%d confirmations, press ctrl-c to stop waiting)\n", receiptConfig.confirmations)
+		var err error
+		if mt, err = waitMinedTransaction(cl, abi, tx); err != nil {
+			infof("stopped waiting for receipt: %s\n", err)
+			infof("transaction %s is still pending\n", tx.Hash().Hex())
+		}
+	}
+	if outputFormat != outputText {
+		printJSON(newTransactionOutput(addr, abi, method, tx, mt))
+	} else if mt != nil {
+		fmt.Print(formatReceipt(addr, abi, mt))
+	}
+}
+
+// effectiveGasPrice returns the gas price paid by a mined transaction
+func effectiveGasPrice(cl backend, tx *types.Transaction, r *types.Receipt) (*big.Int, error) {
+	if tx.Type() != types.DynamicFeeTxType {
+		return tx.GasPrice(), nil
+	}
+	h, err := cl.HeaderByHash(context.Background(), r.BlockHash)
+	if err != nil {
+		return nil, err
+	}
+	return new(big.Int).Add(tx.EffectiveGasTipValue(h.BaseFee), h.BaseFee), nil
+}
+
+// contractLogs returns the logs emitted by the contract at addr
+func contractLogs(addr *common.Address, r *types.Receipt) []*types.Log {
+	logs := make([]*types.Log, 0, len(r.Logs))
+	for _, l := range r.Logs {
+		if l.Address == *addr {
+			logs = append(logs, l)
+		}
+	}
+	return logs
+}
+
+func formatReceipt(addr *common.Address, abi *abi.ABI, mt *minedTransaction) string {
+	var b strings.Builder
+	r := mt.receipt
+	fmt.Fprintf(&b, "receipt:\n")
+	fmt.Fprintf(&b, "  status: %s\n", mt.status())
+	fmt.Fprintf(&b, "  block number: %s\n", r.BlockNumber)
+	fmt.Fprintf(&b, "  gas used: %d\n", r.GasUsed)
+	if mt.gasPrice != nil {
+		fmt.Fprintf(&b, "  effective gas price: %s\n", mt.gasPrice)
+	}
+	events := make([]string, 0, len(r.Logs))
+	for _, l := range contractLogs(addr, r) {
+		ev, eventData, err := decodeLog(abi, l)
+		if err != nil {
+			events = append(events, fmt.Sprintf("    log %d: %s\n", l.Index, err))
+			continue
+		}
+		events = append(events, fmt.Sprintf("    %s: %s\n", ev.Name, formatEventValues(ev.Inputs, eventData)))
+	}
+	if len(events) > 0 {
+		fmt.Fprintf(&b, "  events:\n%s", strings.Join(events, ""))
+	}
+	if mt.revertReason != "" {
+		fmt.Fprintf(&b, "  revert reason: %s\n", mt.revertReason)
+	}
+	return b.String()
+}
+
+var errUnknownEvent = errors.New("unknown event")
+
+func decodeLog(contractABI *abi.ABI, l *types.Log) (*abi.Event, map[string]interface{}, error) {
+	if len(l.Topics) == 0 {
+		return nil, nil, errUnknownEvent
+	}
+	ev, err := contractABI.EventByID(l.Topics[0])
+	if err != nil {
+		return nil, nil, errUnknownEvent
+	}
+	eventData := make(map[string]interface{}, len(ev.Inputs))
+	if len(l.Data) > 0 {
+		if err := contractABI.UnpackIntoMap(eventData, ev.Name, l.Data); err != nil {
+			return nil, nil, err
+		}
+	}
+	var indexed abi.Arguments
+	for _, i := range ev.Inputs {
+		if i.Indexed {
+			indexed = append(indexed, i)
+		}
+	}
+	if err := abi.ParseTopicsIntoMap(eventData, indexed, l.Topics[1:]); err != nil {
+		return nil, nil, err
+	}
+	return ev, eventData, nil
+}
@@ -0,0 +1,175 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+var (
+	errorSelector = crypto.Keccak256([]byte("Error(string)"))[:4]
+	panicSelector = crypto.Keccak256([]byte("Panic(uint256)"))[:4]
+)
+
+// panic codes emitted by the solidity compiler
+var panicCodes = map[uint64]string{
+	0x00: "generic compiler inserted panic",
+	0x01: "assertion failed",
+	0x11: "arithmetic overflow or underflow",
+	0x12: "division or modulo by zero",
+	0x21: "conversion to an invalid enum value",
+	0x22: "access to an incorrectly encoded storage byte array",
+	0x31: "pop on an empty array",
+	0x32: "array index out of bounds",
+	0x41: "too much memory allocated",
+	0x51: "call to a zero-initialized internal function",
+}
+
+type revertError struct {
+	data   []byte
+	reason string
+}
+
+func (e *revertError) Error() string { return "execution reverted: " + e.reason }
+
+// revertData extracts the revert data carried by an rpc error
+func revertData(err error) ([]byte, bool) {
+	var de rpc.DataError
+	if !errors.As(err, &de) {
+		return nil, false
+	}
+	s, ok := de.ErrorData().(string)
+	if !ok {
+		return nil, false
+	}
+	b, err := hexutil.Decode(s)
+	if err != nil {
+		return nil, false
+	}
+	return b, true
+}
+
+// decodeRevert returns a human readable description of the revert data
+func decodeRevert(contractABI *abi.ABI, data []byte) string {
+	if len(data) == 0 {
+		return "no reason given"
+	}
+	if len(data) < 4 {
+		return fmt.Sprintf("invalid revert data %s", hexutil.Encode(data))
+	}
+	switch {
+	case bytes.Equal(data[:4], errorSelector):
+		if reason, err := abi.UnpackRevert(data); err == nil {
+			return fmt.Sprintf("Error(%q)", reason)
+		}
+	case bytes.Equal(data[:4], panicSelector):
+		if code, ok := unpackPanic(data); ok {
+			desc, ok := panicCodes[code.Uint64()]
+			if !code.IsUint64() || !ok {
+				desc = "unknown panic code"
+			}
+			return fmt.Sprintf("Panic(0x%02x): %s", code, desc)
+		}
+	}
+	if contractABI != nil {
+		for _, e := range contractABI.Errors {
+			if !bytes.Equal(data[:4], e.ID[:4]) {
+				continue
+			}
+			values, err := e.Inputs.Unpack(data[4:])
+			if err != nil {
+				continue
+			}
+			return formatCustomError(e, values)
+		}
+	}
+	return fmt.Sprintf("unknown revert data %s", hexutil.Encode(data))
+}
+
+func unpackPanic(data []byte) (*big.Int, bool) {
+	typ, _ := abi.NewType("uint256", "", nil)
+	values, err := (abi.Arguments{{Type: typ}}).Unpack(data[4:])
+	if err != nil {
+		return nil, false
+	}
+	return values[0].(*big.Int), true
+}
+
+func formatCustomError(e abi.Error, values []interface{}) string {
+	fields := make([]string, 0, len(values))
+	for n, i := range e.Inputs {
+		fields = append(fields, fmt.Sprintf("%s=%v", argumentName(n, i), formatValue(values[n])))
+	}
+	return fmt.Sprintf("%s(%s)", e.Name, strings.Join(fields, ", "))
+}
+
+// explainCallError replaces an error carrying revert data by a decoded revert error
+func explainCallError(contractABI *abi.ABI, err error) error {
+	data, ok := revertData(err)
+	if !ok {
+		return err
+	}
+	return &revertError{data: data, reason: decodeRevert(contractABI, data)}
+}
+
+// replayCall runs msg as a call at the given block and returns the decoded revert error, if any
+func replayCall(cl backend, contractABI *abi.ABI, msg ethereum.CallMsg, blockNumber *big.Int) error {
+	_, err := cl.CallContract(context.Background(), msg, blockNumber)
+	if err == nil {
+		return nil
+	}
+	return explainCallError(contractABI, err)
+}
+
+// explainTransactError replays a failed transaction as a call at the pending block to get the revert reason
+func explainTransactError(cl backend, contractABI *abi.ABI, msg ethereum.CallMsg, err error) error {
+	if _, rerr := cl.PendingCallContract(context.Background(), msg); rerr != nil {
+		var re *revertError
+		if errors.As(explainCallError(contractABI, rerr), &re) {
+			return re
+		}
+	}
+	return err
+}
+
+// minedRevertReason replays a reverted transaction on top of its parent block
+func minedRevertReason(cl backend, contractABI *abi.ABI, tx *types.Transaction, r *types.Receipt) (string, error) {
+	from, err := types.Sender(types.LatestSignerForChainID(tx.ChainId()), tx)
+	if err != nil {
+		return "", err
+	}
+	msg := ethereum.CallMsg{
+		From:       from,
+		To:         tx.To(),
+		Gas:        tx.Gas(),
+		Value:      tx.Value(),
+		Data:       tx.Data(),
+		AccessList: tx.AccessList(),
+	}
+	// the gas price of dynamic fee transactions is the fee cap
+	if tx.Type() == types.DynamicFeeTxType {
+		msg.GasFeeCap, msg.GasTipCap = tx.GasFeeCap(), tx.GasTipCap()
+	} else {
+		msg.GasPrice = tx.GasPrice()
+	}
+	parent := new(big.Int).Sub(r.BlockNumber, big.NewInt(1))
+	err = replayCall(cl, contractABI, msg, parent)
+	if err == nil {
+		return "", errors.New("the call succeeds when replayed on the parent block")
+	}
+	var re *revertError
+	if errors.As(err, &re) {
+		return re.reason, nil
+	}
+	return "", err
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"errors"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+const revertTestABI = `[
+	{"type": "error", "name": "InsufficientBalance", "inputs": [{"name": "available", "type": "uint256"}, {"name": "required", "type": "uint256"}]},
+	{"type": "error", "name": "Unauthorized", "inputs": []}
+]`
+
+func TestDecodeRevert(t *testing.T) {
+	contractABI, err := abi.JSON(strings.NewReader(revertTestABI))
+	if err != nil {
+		t.Fatal(err)
+	}
+	uint256, _ := abi.NewType("uint256", "", nil)
+	str, _ := abi.NewType("string", "", nil)
+	pack := func(selector []byte, args abi.Arguments, values ...interface{}) []byte {
+		b, err := args.Pack(values...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return append(append([]byte{}, selector...), b...)
+	}
+	insufficient := contractABI.Errors["InsufficientBalance"]
+	unauthorized := contractABI.Errors["Unauthorized"]
+	for _, i := range []struct {
+		name   string
+		abi    *abi.ABI
+		data   []byte
+		reason string
+	}{
+		{"empty", &contractABI, nil, "no reason given"},
+		{"short", &contractABI, []byte{1, 2, 3}, "invalid revert data 0x010203"},
+		{"error", &contractABI, pack(errorSelector, abi.Arguments{{Type: str}}, "not enough"), `Error("not enough")`},
+		{"panic", nil, pack(panicSelector, abi.Arguments{{Type: uint256}}, big.NewInt(0x11)), "Panic(0x11): arithmetic overflow or underflow"},
+		{"assert", nil, pack(panicSelector, abi.Arguments{{Type: uint256}}, big.NewInt(0x01)), "Panic(0x01): assertion failed"},
+		{"unknown panic", nil, pack(panicSelector, abi.Arguments{{Type: uint256}}, big.NewInt(0x99)), "Panic(0x99): unknown panic code"},
+		{"custom", &contractABI, pack(insufficient.ID[:4], insufficient.Inputs, big.NewInt(1), big.NewInt(2)), "InsufficientBalance(available=1, required=2)"},
+		{"custom without inputs", &contractABI, unauthorized.ID[:4], "Unauthorized()"},
+		{"custom without abi", nil, unauthorized.ID[:4], "unknown revert data " + hexutil.Encode(unauthorized.ID[:4])},
+		{"unknown selector", &contractABI, []byte{0xde, 0xad, 0xbe, 0xef}, "unknown revert data 0xdeadbeef"},
+		{"invalid error", &contractABI, append(append([]byte{}, errorSelector...), 1), "unknown revert data " + hexutil.Encode(append(append([]byte{}, errorSelector...), 1))},
+	} {
+		if r := decodeRevert(i.abi, i.data); r != i.reason {
+			t.Errorf("%s: expecting %s, got %s", i.name, i.reason, r)
+		}
+	}
+}
+
+type testDataError struct{ data interface{} }
+
+func (e *testDataError) Error() string          { return "execution reverted" }
+func (e *testDataError) ErrorData() interface{} { return e.data }
+
+func TestExplainCallError(t *testing.T) {
+	err := explainCallError(nil, &testDataError{data: "0xdeadbeef"})
+	var re *revertError
+	if !errors.As(err, &re) || re.reason != "unknown revert data 0xdeadbeef" {
+		t.Errorf("unexpected error %v", err)
+	}
+	for _, i := range []error{errors.New("header not found"), &testDataError{data: 1}, &testDataError{data: "0xz"}} {
+		if err = explainCallError(nil, i); err != i {
+			t.Errorf("%v: expecting the error unchanged, got %v", i, err)
+		}
+	}
+}
@@ -0,0 +1,288 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"path/filepath"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// backend is the chain access used by the commands. it's implemented by the
+// rpc client and by the simulated chain
+type backend interface {
+	bind.ContractBackend
+	bind.DeployBackend
+	bind.PendingContractCaller
+	HeaderByHash(ctx context.Context, hash common.Hash) (*types.Header, error)
+	BalanceAt(ctx context.Context, account common.Address, blockNumber *big.Int) (*big.Int, error)
+	NonceAt(ctx context.Context, account common.Address, blockNumber *big.Int) (uint64, error)
+	ChainID(ctx context.Context) (*big.Int, error)
+	// HeaderByTag returns the latest, safe, finalized or earliest block header
+	HeaderByTag(ctx context.Context, tag string) (*types.Header, error)
+}
+
+// rpcBackend is the backend of rpc endpoints. it keeps the rpc client for the block tags the
+// ethereum client doesn't support
+type rpcBackend struct {
+	*ethclient.Client
+	rpc *rpc.Client
+}
+
+func (b *rpcBackend) HeaderByTag(ctx context.Context, tag string) (*types.Header, error) {
+	var r *types.Header
+	if err := b.rpc.CallContext(ctx, &r, "eth_getBlockByNumber", tag, false); err != nil {
+		return nil, err
+	}
+	if r == nil {
+		return nil, ethereum.NotFound
+	}
+	return r, nil
+}
+
+// sessionContract is a named contract of the session
+type sessionContract struct {
+	name     string
+	address  common.Address
+	abi      *abi.ABI
+	bytecode []byte
+	// libraries the artifact bytecode must be linked to
+	unlinked []string
+	// where the abi and bytecode were read from
+	abiFile      string
+	artifact     string
+	bytecodeFile string
+}
+
+type session struct {
+	url    string
+	client backend
+	sim    *simulatedBackend
+	// chainID is the chain of the endpoint, pinnedChainID the one the session must be on
+	chainID       *big.Int
+	pinnedChainID *big.Int
+	// the selected contract
+	*sessionContract
+	contracts []*sessionContract
+	// transactions sent in the session and the nonce set for the next one
+	transactions []*sentTransaction
+	nextNonce    *uint64
+	close        func()
+}
+
+var sess = &session{close: func() {}}
+
+var (
+	errMissingURL     = errors.New("missing client url")
+	errMissingABI     = errors.New("missing abi file")
+	errInvalidAddress = errors.New("invalid contract address")
+	errNoContract     = errors.New("contract address not set")
+	errContractExists = errors.New("contract name already in use")
+	errContractInUse  = errors.New("can't remove the selected contract")
+	errChainMismatch  = errors.New("chain id mismatch")
+)
+
+// chainNames are the names of well known chains
+var chainNames = map[uint64]string{
+	1:        "mainnet",
+	5:        "goerli",
+	10:       "optimism",
+	56:       "bsc",
+	100:      "gnosis",
+	137:      "polygon",
+	1337:     "dev",
+	8453:     "base",
+	17000:    "holesky",
+	31337:    "hardhat",
+	42161:    "arbitrum",
+	43114:    "avalanche",
+	80001:    "mumbai",
+	11155111: "sepolia",
+}
+
+// chainDescription returns the name and id of a chain
+func chainDescription(chainID *big.Int) string {
+	if chainID.IsUint64() {
+		if name, ok := chainNames[chainID.Uint64()]; ok {
+			return name + " " + chainID.String()
+		}
+	}
+	return "chain " + chainID.String()
+}
+
+func openSession(url string) error {
+	if url == "" {
+		return errMissingURL
+	}
+	rc, err := rpc.Dial(url)
+	if err != nil {
+		return fmt.Errorf("can't dial client: %w", err)
+	}
+	cl := &rpcBackend{Client: ethclient.NewClient(rc), rpc: rc}
+	chainID, err := cl.ChainID(context.Background())
+	if err != nil {
+		cl.Close()
+		return fmt.Errorf("can't get chain id: %w", err)
+	}
+	sess.url = url
+	sess.client = cl
+	sess.chainID = chainID
+	sess.close = cl.Close
+	return nil
+}
+
+// openSimulatedSession starts a simulated chain, signing with the first dev account
+func openSimulatedSession() {
+	sim := newSimulatedBackend()
+	sess.client = sim
+	sess.sim = sim
+	sess.chainID, _ = sim.ChainID(context.Background())
+	sess.close = func() { sim.Close() }
+	txSigner = newKeySigner(sim.keys[0])
+}
+
+// openContract reads the contract from the abi file and adds it to the session. without an
+// address the address of the chain is taken from the artifact when it's there
+func (s *session) openContract(name, abiFile, contract, address string) (*sessionContract, error) {
+	if address != "" && !common.IsHexAddress(address) {
+		return nil, errInvalidAddress
+	}
+	artifact, err := loadArtifact(abiFile, contract, interactive)
+	if err != nil {
+		return nil, fmt.Errorf("can't read abi: %w", err)
+	}
+	if abiFile, err = filepath.Abs(abiFile); err != nil {
+		return nil, err
+	}
+	c := &sessionContract{
+		name:     name,
+		address:  common.HexToAddress(address),
+		abi:      artifact.abi,
+		bytecode: artifact.bytecode,
+		unlinked: artifact.unlinked,
+		abiFile:  abiFile,
+		artifact: artifact.name,
+	}
+	if c.name == "" {
+		c.name = artifact.name
+	}
+	if address == "" && len(artifact.networks) != 0 && s.sim == nil {
+		if addr, ok := artifact.networkAddress(s.chainID); ok {
+			infof("using the %s address on %s from the artifact: %s\n", artifact.name, chainDescription(s.chainID), addr.Hex())
+			c.address = addr
+		}
+	}
+	if err = s.addContract(c); err != nil {
+		return nil, fmt.Errorf("%w: %s", err, c.name)
+	}
+	return c, nil
+}
+
+// deploySimulated deploys the selected contract to the simulated chain when it has no address,
+// using the bytecode in the file or in the artifact
+func (s *session) deploySimulated(bytecodeFile string, constructorArgs []string) error {
+	if s.sessionContract == nil || s.address != (common.Address{}) {
+		return nil
+	}
+	bytecode := s.bytecode
+	if bytecodeFile != "" {
+		var err error
+		if bytecode, err = readBytecode(bytecodeFile, s.artifact); err != nil {
+			return fmt.Errorf("can't read bytecode: %w", err)
+		}
+		if s.bytecodeFile, err = filepath.Abs(bytecodeFile); err != nil {
+			return err
+		}
+		s.bytecode = bytecode
+	}
+	if bytecode == nil {
+		if len(s.unlinked) != 0 {
+			return fmt.Errorf("can't deploy contract: %w", unlinkedError(s.unlinked))
+		}
+		return nil
+	}
+	args, err := parseArguments(s.abi.Constructor.Inputs, constructorArgs)
+	if err != nil {
+		return fmt.Errorf("can't parse constructor arguments: %w", err)
+	}
+	addr, tx, err := deployContract(s.sim, s.abi, bytecode, args, nil)
+	if err != nil {
+		return fmt.Errorf("can't deploy contract: %w", err)
+	}
+	if _, err = bind.WaitDeployed(context.Background(), s.sim, tx); err != nil {
+		return fmt.Errorf("can't deploy contract: %w", err)
+	}
+	infof("contract deployed at %s\n", addr.Hex())
+	s.address = addr
+	return nil
+}
+
+// addContract adds a named contract to the session
+func (s *session) addContract(c *sessionContract) error {
+	if s.findContract(c.name) != nil {
+		return errContractExists
+	}
+	s.contracts = append(s.contracts, c)
+	return nil
+}
+
+func (s *session) findContract(name string) *sessionContract {
+	for _, i := range s.contracts {
+		if i.name == name {
+			return i
+		}
+	}
+	return nil
+}
+
+// selectContract makes the named contract the target of the commands
+func (s *session) selectContract(name string) error {
+	c := s.findContract(name)
+	if c == nil {
+		return fmt.Errorf("%w: %s", errUnknownContract, name)
+	}
+	s.sessionContract = c
+	return nil
+}
+
+func (s *session) removeContract(name string) error {
+	for n, i := range s.contracts {
+		if i.name != name {
+			continue
+		}
+		if i == s.sessionContract {
+			return errContractInUse
+		}
+		s.contracts = append(s.contracts[:n], s.contracts[n+1:]...)
+		return nil
+	}
+	return fmt.Errorf("%w: %s", errUnknownContract, name)
+}
+
+// checkChainID verifies the chain the endpoint is on before sending transactions
+func (s *session) checkChainID(chainID *big.Int) error {
+	expected := s.pinnedChainID
+	if expected == nil {
+		expected = s.chainID
+	}
+	if expected != nil && expected.Cmp(chainID) != 0 {
+		return fmt.Errorf("%w: the endpoint is on %s, expecting %s", errChainMismatch, chainDescription(chainID), chainDescription(expected))
+	}
+	return nil
+}
+
+// contract returns the address of the session contract
+func (s *session) contract() (*common.Address, error) {
+	if s.address == (common.Address{}) {
+		return nil, errNoContract
+	}
+	return &s.address, nil
+}
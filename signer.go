@@ -1,31 +1,59 @@
 package main
 
-import "crypto/ecdsa"
+import (
+	"crypto/ecdsa"
+	"errors"
+	"math/big"
 
-type signerKind int
-
-const (
-	signerNone signerKind = iota
-	signerKey
-	signerLedger
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
 )
 
-type signer struct {
-	key    *ecdsa.PrivateKey
-	ledger interface{}
+var errNoSigner = errors.New("signer not set")
+
+// signer signs the transactions of the session
+type signer interface {
+	// address is the account the transactions are sent from
+	address() common.Address
+	transactor(chainID *big.Int) (*bind.TransactOpts, error)
+	// profile describes the signer in the config file, nil if it can't be saved
+	profile() *profileSigner
+	// close wipes or releases the key
+	close()
 }
 
-func newKeySigner(key *ecdsa.PrivateKey) signer { return signer{key: key} }
-func newLedgerSigner(v interface{}) signer      { return signer{ledger: v} }
+var txSigner signer
 
-func (s signer) kind() signerKind {
-	if s.key != nil {
-		return signerKey
+// setSigner replaces the signer of the session, closing the previous one
+func setSigner(s signer) {
+	if txSigner != nil && txSigner != s {
+		txSigner.close()
 	}
-	if s.ledger != nil {
-		return signerLedger
+	txSigner = s
+}
+
+// keySigner signs with a private key held in memory
+type keySigner struct {
+	key *ecdsa.PrivateKey
+	// the files the key was read from, saved in the profiles
+	keyFile      string
+	passwordFile string
+}
+
+func newKeySigner(key *ecdsa.PrivateKey) *keySigner { return &keySigner{key: key} }
+
+func (s *keySigner) address() common.Address { return crypto.PubkeyToAddress(s.key.PublicKey) }
+
+func (s *keySigner) transactor(chainID *big.Int) (*bind.TransactOpts, error) {
+	return bind.NewKeyedTransactorWithChainID(s.key, chainID)
+}
+
+func (s *keySigner) profile() *profileSigner {
+	if s.keyFile == "" {
+		return nil
 	}
-	return signerNone
+	return &profileSigner{Key: s.keyFile, PasswordFile: s.passwordFile}
 }
 
-var txSigner = signer{}
+func (s *keySigner) close() {}
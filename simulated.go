@@ -0,0 +1,79 @@
+package main
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind/backends"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+const (
+	devAccounts       = 10
+	simulatedGasLimit = 30000000
+)
+
+// every dev account starts with 1000 ether
+var devBalance = new(big.Int).Mul(big.NewInt(1000), big.NewInt(params.Ether))
+
+// simulatedBackend is an in-process chain with prefunded dev accounts
+type simulatedBackend struct {
+	*backends.SimulatedBackend
+	keys       []*ecdsa.PrivateKey
+	autoCommit bool
+}
+
+func newSimulatedBackend() *simulatedBackend {
+	keys := devKeys(devAccounts)
+	alloc := make(core.GenesisAlloc, len(keys))
+	for _, i := range keys {
+		alloc[crypto.PubkeyToAddress(i.PublicKey)] = core.GenesisAccount{Balance: devBalance}
+	}
+	return &simulatedBackend{
+		SimulatedBackend: backends.NewSimulatedBackend(alloc, simulatedGasLimit),
+		keys:             keys,
+		autoCommit:       true,
+	}
+}
+
+// devKeys derives deterministic keys so every run has the same accounts
+func devKeys(n int) []*ecdsa.PrivateKey {
+	r := make([]*ecdsa.PrivateKey, 0, n)
+	for i := 0; i < n; i++ {
+		k, err := crypto.ToECDSA(crypto.Keccak256([]byte(fmt.Sprintf("scui dev account %d", i))))
+		if err != nil {
+			panic(err)
+		}
+		r = append(r, k)
+	}
+	return r
+}
+
+func (b *simulatedBackend) ChainID(ctx context.Context) (*big.Int, error) {
+	return b.Blockchain().Config().ChainID, nil
+}
+
+// HeaderByTag returns the head block for the safe and finalized tags, the simulated chain
+// doesn't reorganise
+func (b *simulatedBackend) HeaderByTag(ctx context.Context, tag string) (*types.Header, error) {
+	if tag == "earliest" {
+		return b.HeaderByNumber(ctx, big.NewInt(0))
+	}
+	return b.HeaderByNumber(ctx, nil)
+}
+
+// SendTransaction mines the transaction immediately when auto commit is enabled
+func (b *simulatedBackend) SendTransaction(ctx context.Context, tx *types.Transaction) error {
+	if err := b.SimulatedBackend.SendTransaction(ctx, tx); err != nil {
+		return err
+	}
+	if b.autoCommit {
+		b.Commit()
+	}
+	return nil
+}
@@ -0,0 +1,248 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/c-bata/go-prompt"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/spf13/cobra"
+)
+
+var errNoPendingTransactions = errors.New("no pending transactions")
+
+// cancelGas is the gas of the self-transfer that cancels a transaction
+const cancelGas = 21000
+
+// sentTransaction is a transaction sent in the session
+type sentTransaction struct {
+	tx       *types.Transaction
+	from     common.Address
+	contract string
+	method   string
+	// abi of the called contract, to decode the transaction when it's replaced
+	abi    *abi.ABI
+	sentAt time.Time
+	// replacedBy is the transaction sent with the same nonce to speed up or cancel this one
+	replacedBy *sentTransaction
+}
+
+// recordTransaction adds a sent transaction to the session history. the explicit nonce is
+// used once
+func (s *session) recordTransaction(from common.Address, contract, method string, contractABI *abi.ABI, tx *types.Transaction) *sentTransaction {
+	r := &sentTransaction{tx: tx, from: from, contract: contract, method: method, abi: contractABI, sentAt: time.Now()}
+	s.transactions = append(s.transactions, r)
+	if s.nextNonce != nil && *s.nextNonce == tx.Nonce() {
+		s.nextNonce = nil
+	}
+	return r
+}
+
+func (t *sentTransaction) description() string {
+	switch {
+	case t.method == "cancel":
+		return "cancel (self-transfer)"
+	case t.method == "" && len(t.tx.Data()) == 0:
+		return "transfer"
+	case t.method == "":
+		return "unknown method"
+	case t.contract == "":
+		return t.method
+	}
+	return t.contract + "." + t.method
+}
+
+// status returns the state of the transaction and whether it's still pending
+func (t *sentTransaction) status(cl backend) (string, bool) {
+	r, err := cl.TransactionReceipt(context.Background(), t.tx.Hash())
+	if err == nil {
+		if r.Status == types.ReceiptStatusSuccessful {
+			return fmt.Sprintf("success (block %s)", r.BlockNumber), false
+		}
+		return fmt.Sprintf("failed (block %s)", r.BlockNumber), false
+	}
+	nonce, err := cl.NonceAt(context.Background(), t.from, nil)
+	if err != nil {
+		return fmt.Sprintf("unknown (%s)", err), false
+	}
+	if nonce > t.tx.Nonce() {
+		if t.replacedBy != nil {
+			return "replaced by " + t.replacedBy.tx.Hash().Hex(), false
+		}
+		return "dropped (nonce used by another transaction)", false
+	}
+	return "pending", true
+}
+
+func cmdTxList(cmd *cobra.Command, args []string) error {
+	if len(sess.transactions) == 0 {
+		fmt.Printf("no transactions sent in this session\n")
+	}
+	for n, i := range sess.transactions {
+		status, _ := i.status(sess.client)
+		fmt.Printf("%d  %s  nonce %d  %s  %s  %s\n", n, i.tx.Hash().Hex(), i.tx.Nonce(), i.description(), i.sentAt.Format("15:04:05"), status)
+	}
+	if sess.nextNonce != nil {
+		fmt.Printf("next transaction nonce: %d\n", *sess.nextNonce)
+	}
+	return nil
+}
+
+func cmdTxNonce(cmd *cobra.Command, args []string) error {
+	if txSigner == nil {
+		return errNoSigner
+	}
+	from := txSigner.address()
+	pending, err := sess.client.PendingNonceAt(context.Background(), from)
+	if err != nil {
+		return err
+	}
+	latest, err := sess.client.NonceAt(context.Background(), from, nil)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("account %s\n  mined nonce: %d\n  pending nonce: %d\n", from.Hex(), latest, pending)
+	def := "pending"
+	if sess.nextNonce != nil {
+		def = strconv.FormatUint(*sess.nextNonce, 10)
+	}
+	for {
+		v, ok := inputTextWithDefault("nonce of the next transaction (%s): ", def)
+		if !ok {
+			return errAborted
+		}
+		if v == "pending" {
+			sess.nextNonce = nil
+			return nil
+		}
+		nonce, err := strconv.ParseUint(v, 10, 64)
+		if err != nil {
+			fmt.Printf("invalid nonce, enter a number or pending\n")
+			continue
+		}
+		if nonce < latest {
+			fmt.Printf("nonce %d is already used\n", nonce)
+			continue
+		}
+		sess.nextNonce = &nonce
+		return nil
+	}
+}
+
+func cmdTxSpeedUp(cmd *cobra.Command, args []string) error {
+	t, err := choosePendingTransaction("transaction to speed up (%s): ")
+	if err != nil {
+		return err
+	}
+	return replaceTransaction(sess.client, t, false)
+}
+
+func cmdTxCancel(cmd *cobra.Command, args []string) error {
+	t, err := choosePendingTransaction("transaction to cancel (%s): ")
+	if err != nil {
+		return err
+	}
+	return replaceTransaction(sess.client, t, true)
+}
+
+// choosePendingTransaction asks for one of the pending transactions of the session
+func choosePendingTransaction(pr string) (*sentTransaction, error) {
+	pending := make([]*sentTransaction, 0, len(sess.transactions))
+	choices := make([]prompt.Suggest, 0, len(sess.transactions))
+	for _, i := range sess.transactions {
+		if _, ok := i.status(sess.client); ok {
+			pending = append(pending, i)
+			choices = append(choices, prompt.Suggest{Text: i.tx.Hash().Hex(), Description: fmt.Sprintf("nonce %d  %s", i.tx.Nonce(), i.description())})
+		}
+	}
+	if len(pending) == 0 {
+		return nil, errNoPendingTransactions
+	}
+	showSuggestions(choices)
+	h, ok := inputMultiChoice(pr, choices[len(choices)-1].Text, choices, showSuggestions)
+	if !ok {
+		return nil, errAborted
+	}
+	for n, i := range choices {
+		if i.Text == h {
+			return pending[n], nil
+		}
+	}
+	return nil, errAborted
+}
+
+// replaceTransaction sends t again with higher fees or, to cancel it, sends a 0 value
+// self-transfer with its nonce
+func replaceTransaction(cl backend, t *sentTransaction, cancel bool) error {
+	if txSigner == nil {
+		return errNoSigner
+	}
+	if from := txSigner.address(); from != t.from {
+		return fmt.Errorf("%w: the transaction is from %s, the signer is %s", errWrongSigner, t.from.Hex(), from.Hex())
+	}
+	opts, err := newTransactor(cl)
+	if err != nil {
+		return err
+	}
+	params, err := replacementFees(cl, t.tx)
+	if err != nil {
+		return err
+	}
+	if params.gasPrice != nil {
+		fmt.Printf("gas price: %s gwei\n", formatGwei(t.tx.GasPrice()))
+		opts.GasPrice = inputBigIntWithDefault("replacement gas price (%s): ", params.gasPrice)
+	} else {
+		fmt.Printf("max priority fee: %s gwei, max fee: %s gwei\n", formatGwei(t.tx.GasTipCap()), formatGwei(t.tx.GasFeeCap()))
+		opts.GasTipCap = inputBigIntWithDefault("replacement max priority fee (%s): ", params.tipCap)
+		opts.GasFeeCap = inputBigIntWithDefault("replacement max fee (%s): ", params.feeCap)
+		if opts.GasFeeCap.Cmp(opts.GasTipCap) < 0 {
+			return errFeeBelowTip
+		}
+	}
+	to, data, contract, method, contractABI := t.tx.To(), t.tx.Data(), t.contract, t.method, t.abi
+	opts.Nonce = new(big.Int).SetUint64(t.tx.Nonce())
+	opts.GasLimit, opts.Value = t.tx.Gas(), t.tx.Value()
+	if cancel {
+		to, data, contract, method, contractABI = &t.from, nil, "", "cancel", nil
+		opts.GasLimit, opts.Value = cancelGas, new(big.Int)
+	}
+	chainID, err := cl.ChainID(context.Background())
+	if err != nil {
+		return err
+	}
+	tx := buildTransaction(chainID, opts, to, data)
+	fmt.Print(formatRawTransaction(tx, chainID, &t.from, contractABI))
+	send, ok := inputYesNo("send replacement transaction? (%s): ", false)
+	if !ok || !send {
+		return errAborted
+	}
+	signed, err := opts.Signer(t.from, tx)
+	if err != nil {
+		return err
+	}
+	if err = cl.SendTransaction(context.Background(), signed); err != nil {
+		if strings.Contains(err.Error(), "underpriced") {
+			return fmt.Errorf("%w, raise the fees", err)
+		}
+		return err
+	}
+	t.replacedBy = sess.recordTransaction(t.from, contract, method, contractABI, signed)
+	if contractABI == nil {
+		contractABI = &abi.ABI{}
+	}
+	if to == nil {
+		// a contract creation, the address depends only on the sender and the nonce
+		addr := crypto.CreateAddress(t.from, signed.Nonce())
+		to = &addr
+	}
+	showTransaction(cl, to, contractABI, method, signed)
+	return nil
+}
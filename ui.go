@@ -1,143 +1,271 @@
 package main
 
 import (
-	"encoding/json"
 	"fmt"
+	"io/ioutil"
 	"reflect"
 	"sort"
+	"strings"
 
 	"github.com/c-bata/go-prompt"
 	"github.com/ethereum/go-ethereum/accounts/abi"
 )
 
-func methodsMenus(methods map[string]abi.Method) (*menuCompleter, *menuCompleter) {
-	constantNode := &menuCompleter{suggestion: &prompt.Suggest{
-		Text:        "constant",
-		Description: "make a call to a constant method",
-	}}
-	transactNode := &menuCompleter{suggestion: &prompt.Suggest{
-		Text:        "transact",
-		Description: "make a transaction to a method",
-	}}
-	names := make([]string, 0, len(methods))
-	for i := range methods {
-		names = append(names, i)
-	}
-	sort.Strings(names)
-	for _, name := range names {
-		m := methods[name]
-		n := &menuCompleter{
-			suggestion: &prompt.Suggest{
-				Text:        name,
-				Description: m.String(),
-			},
-		}
-		if m.IsConstant() {
-			n.parent = constantNode
-			constantNode.sub = append(constantNode.sub, n)
-		} else {
-			n.parent = transactNode
-			transactNode.sub = append(transactNode.sub, n)
+func inputArguments(args abi.Arguments, isFilter bool) ([]interface{}, error) {
+	r := make([]interface{}, 0, len(args))
+	for n, i := range args {
+		v, err := inputValue(argumentName(n, i), i.Type)
+		if err != nil {
+			return nil, err
 		}
+		r = append(r, v)
 	}
-	constantNode.sub = append(constantNode.sub, tailCommands...)
-	transactNode.sub = append(transactNode.sub, tailCommands...)
-	return constantNode, transactNode
+	return r, nil
 }
 
-func eventsMenu(events map[string]abi.Event) (*menuCompleter, *menuCompleter, *menuCompleter) {
-	eventsNode := &menuCompleter{suggestion: &prompt.Suggest{
-		Text:        "events",
-		Description: "filter/watch events",
-	}}
-	listNode := &menuCompleter{parent: eventsNode, suggestion: &prompt.Suggest{
-		Text:        "list",
-		Description: "list event",
-	}}
-	watchNode := &menuCompleter{parent: eventsNode, suggestion: &prompt.Suggest{
-		Text:        "watch",
-		Description: "watch event",
-	}}
-	eventsNode.sub = append([]*menuCompleter{listNode, watchNode}, tailCommands...)
-	eventsNames := make([]string, 0, len(events))
-	for i := range events {
-		eventsNames = append(eventsNames, i)
-	}
-	sort.Strings(eventsNames)
-	for _, name := range eventsNames {
-		sug := &prompt.Suggest{Text: name, Description: events[name].String()}
-		listNode.sub = append(listNode.sub, &menuCompleter{suggestion: sug, parent: listNode})
-		watchNode.sub = append(watchNode.sub, &menuCompleter{suggestion: sug, parent: watchNode})
-	}
-	listNode.sub = append(listNode.sub, tailCommands...)
-	watchNode.sub = append(watchNode.sub, tailCommands...)
-	return eventsNode, listNode, watchNode
+// inputValue walks the type tree asking for each tuple component by name
+func inputValue(name string, t abi.Type) (interface{}, error) {
+	switch {
+	case t.T == abi.TupleTy:
+		fmt.Printf("%s (%s):\n", name, t.String())
+		r := reflect.New(t.GetType()).Elem()
+		for n, i := range t.TupleElems {
+			v, err := inputValue(name+"."+tupleFieldName(t, n), *i)
+			if err != nil {
+				return nil, err
+			}
+			r.Field(n).Set(reflect.ValueOf(v))
+		}
+		return r.Interface(), nil
+	case (t.T == abi.SliceTy || t.T == abi.ArrayTy) && t.Elem.T == abi.TupleTy:
+		var r reflect.Value
+		if t.T == abi.ArrayTy {
+			r = reflect.New(t.GetType()).Elem()
+		} else {
+			var sz int
+			for {
+				var ok bool
+				if sz, ok = inputIntWithDefault(fmt.Sprintf("%s items (%%d): ", name), 0); !ok {
+					return nil, errAborted
+				}
+				if sz >= 0 {
+					break
+				}
+				fmt.Printf("invalid number of items: %d\n", sz)
+			}
+			r = reflect.MakeSlice(t.GetType(), sz, sz)
+		}
+		for n := 0; n < r.Len(); n++ {
+			v, err := inputValue(fmt.Sprintf("%s[%d]", name, n), *t.Elem)
+			if err != nil {
+				return nil, err
+			}
+			r.Index(n).Set(reflect.ValueOf(v))
+		}
+		return r.Interface(), nil
+	}
+	pr := name + " (" + t.String()
+	if hint := typeHint(t); hint != "" {
+		pr += ", " + hint
+	}
+	pr += "): "
+	for {
+		val := inputText(pr)
+		switch strings.TrimSpace(val) {
+		case "":
+			fmt.Printf("....\n")
+			continue
+		case "..":
+			fmt.Println("aborted")
+			return nil, errAborted
+		}
+		v, err := parseValue(t, val)
+		if err != nil {
+			fmt.Printf("invalid %s: %s\n", name, err)
+			continue
+		}
+		return v, nil
+	}
 }
 
-func inputArguments(args abi.Arguments, isFilter bool) ([]interface{}, error) {
-	r := make([]interface{}, 0, len(args))
-	for _, i := range args {
-		for {
-			val := inputText(i.Name + " (" + i.Type.String() + "): ")
-			if val == "" {
-				fmt.Printf("....\n")
-				continue
+// inputFilters asks for the values of the indexed fields. every indexed field keeps its topic
+// position, without values it matches anything. several values match any of them
+func inputFilters(inputs abi.Arguments) ([][]interface{}, error) {
+	r := make([][]interface{}, 0, 4)
+	for _, i := range inputs {
+		if !i.Indexed {
+			continue
+		}
+		pr := fmt.Sprintf("field %s (%s) is indexed. filter? (%%s): ", i.Name, i.Type.String())
+		filterField, ok := inputYesNo(pr, false)
+		if !ok {
+			return nil, errAborted
+		}
+		var values []interface{}
+		for filterField {
+			v := inputText(fmt.Sprintf("field value %d (@file to read the values from a file, none to finish): ", len(values)+1))
+			if v == "" {
+				break
 			}
-			v, err := unmarshalValue(val, i.Type.GetType())
+			fvs, err := parseFilterValues(i.Type, v)
 			if err != nil {
-				return nil, err
+				fmt.Printf("can't parse value: %s\n", err)
+				continue
 			}
-			r = append(r, v)
-			break
+			values = append(values, fvs...)
 		}
+		r = append(r, values)
 	}
 	return r, nil
 }
 
-func unmarshalValue(val string, t reflect.Type) (interface{}, error) {
-	if t.Elem().Kind() == reflect.Ptr {
-		t = t.Elem()
+// parseFilterValues parses a filter value, or with a @ prefix the values of a file, one per line.
+// empty lines and lines starting with # are skipped
+func parseFilterValues(t abi.Type, v string) ([]interface{}, error) {
+	if !strings.HasPrefix(v, "@") {
+		fv, err := parseValue(t, v)
+		if err != nil {
+			return nil, err
+		}
+		return []interface{}{fv}, nil
 	}
-	v := reflect.New(t).Interface()
-	b, err := json.Marshal(v)
+	b, err := ioutil.ReadFile(v[1:])
 	if err != nil {
 		return nil, err
 	}
-	if len(b) > 1 && b[0] == '"' {
-		val = "\"" + val + "\""
+	var r []interface{}
+	for n, i := range strings.Split(string(b), "\n") {
+		i = strings.TrimSpace(i)
+		if i == "" || strings.HasPrefix(i, "#") {
+			continue
+		}
+		fv, err := parseValue(t, i)
+		if err != nil {
+			return nil, fmt.Errorf("%s line %d: %w", v[1:], n+1, err)
+		}
+		r = append(r, fv)
+	}
+	if len(r) == 0 {
+		return nil, fmt.Errorf("no values in %s", v[1:])
 	}
-	if err = json.Unmarshal([]byte(val), v); err != nil {
+	return r, nil
+}
+
+// inputEventNames asks for the events to select, all of them if none is chosen
+func inputEventNames(contractABI *abi.ABI) ([]string, error) {
+	sugs := make([]prompt.Suggest, 0, len(contractABI.Events))
+	for name, e := range contractABI.Events {
+		sugs = append(sugs, prompt.Suggest{Text: name, Description: e.String()})
+	}
+	sort.Slice(sugs, func(i, j int) bool { return sugs[i].Text < sugs[j].Text })
+	for {
+		v := prompt.Input("events (all): ", func(doc prompt.Document) []prompt.Suggest {
+			return prompt.FilterHasPrefix(sugs, doc.GetWordBeforeCursor(), false)
+		})
+		if strings.TrimSpace(v) == ".." {
+			return nil, errAborted
+		}
+		r, err := parseEventNames(contractABI, strings.FieldsFunc(v, func(c rune) bool { return c == ' ' || c == ',' }))
+		if err != nil {
+			fmt.Println(err)
+			continue
+		}
+		return r, nil
+	}
+}
+
+// parseEventNames checks the names of the events, removing the repeated ones
+func parseEventNames(contractABI *abi.ABI, names []string) ([]string, error) {
+	r := make([]string, 0, len(names))
+	seen := make(map[string]bool, len(names))
+	for _, i := range names {
+		if _, ok := contractABI.Events[i]; !ok {
+			return nil, fmt.Errorf("%w: %s", errUnknownEventName, i)
+		}
+		if !seen[i] {
+			seen[i] = true
+			r = append(r, i)
+		}
+	}
+	return r, nil
+}
+
+func argumentName(n int, arg abi.Argument) string {
+	if arg.Name == "" {
+		return fmt.Sprintf("arg%d", n)
+	}
+	return arg.Name
+}
+
+func parseKeyValues(values []string) (map[string]string, error) {
+	r := make(map[string]string, len(values))
+	for _, i := range values {
+		parts := strings.SplitN(i, "=", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("expecting name=value: %s", i)
+		}
+		if _, ok := r[parts[0]]; ok {
+			return nil, fmt.Errorf("duplicated value: %s", parts[0])
+		}
+		r[parts[0]] = parts[1]
+	}
+	return r, nil
+}
+
+func parseArguments(args abi.Arguments, values []string) ([]interface{}, error) {
+	kv, err := parseKeyValues(values)
+	if err != nil {
 		return nil, err
 	}
-	return v, nil
+	r := make([]interface{}, 0, len(args))
+	for n, i := range args {
+		name := argumentName(n, i)
+		val, ok := kv[name]
+		if !ok {
+			return nil, fmt.Errorf("missing argument: %s", name)
+		}
+		delete(kv, name)
+		v, err := parseValue(i.Type, val)
+		if err != nil {
+			return nil, fmt.Errorf("can't parse argument %s: %w", name, err)
+		}
+		r = append(r, v)
+	}
+	for name := range kv {
+		return nil, fmt.Errorf("unknown argument: %s", name)
+	}
+	return r, nil
 }
 
-func inputFilters(inputs abi.Arguments) ([][]interface{}, error) {
-	// get filters
+// parseFilters parses name=value filters. a name can be repeated to match any of its values
+func parseFilters(inputs abi.Arguments, values []string) ([][]interface{}, error) {
+	kv := make(map[string][]string, len(values))
+	for _, i := range values {
+		parts := strings.SplitN(i, "=", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("expecting name=value: %s", i)
+		}
+		kv[parts[0]] = append(kv[parts[0]], parts[1])
+	}
 	r := make([][]interface{}, 0, 4)
-	for _, i := range inputs {
+	for n, i := range inputs {
 		if !i.Indexed {
 			continue
 		}
-		pr := fmt.Sprintf("field %s (%s) is indexed. filter? (%%s): ", i.Name, i.Type.String())
-		if filterField, ok := inputYesNo(pr, false); !ok {
-			return nil, errAborted
-		} else if filterField {
-			for {
-				v := inputText("field value (none): ")
-				if v == "" {
-					r = append(r, nil)
-					break
-				}
-				fv, err := unmarshalValue(v, i.Type.GetType())
-				if err != nil {
-					fmt.Printf("can't parse value: %s\n", err)
-					continue
-				}
-				r = append(r, []interface{}{fv})
-				break
+		name := argumentName(n, i)
+		var fvs []interface{}
+		for _, val := range kv[name] {
+			v, err := parseFilterValues(i.Type, val)
+			if err != nil {
+				return nil, fmt.Errorf("can't parse filter %s: %w", name, err)
 			}
+			fvs = append(fvs, v...)
 		}
+		delete(kv, name)
+		r = append(r, fvs)
+	}
+	for name := range kv {
+		return nil, fmt.Errorf("unknown or not indexed field: %s", name)
 	}
 	return r, nil
 }
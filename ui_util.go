@@ -11,6 +11,7 @@ import (
 	"strconv"
 	"strings"
 	"syscall"
+	"time"
 
 	"github.com/c-bata/go-prompt"
 	"github.com/ethereum/go-ethereum/accounts/keystore"
@@ -39,14 +40,19 @@ type menuCompleter struct {
 	suggestion *prompt.Suggest
 	sub        []*menuCompleter
 	parent     *menuCompleter
+	cmd        *cobra.Command
+	args       []string
 }
 
-func newRootNode(entries []*menuCompleter) *menuCompleter {
-	r := &menuCompleter{sub: append(make([]*menuCompleter, 0, len(entries)+3), entries...)}
-	for _, i := range r.sub {
-		i.parent = r
+func newRootNode(cmd *cobra.Command) *menuCompleter {
+	cmds := cmd.Commands()
+	r := &menuCompleter{cmd: cmd, sub: make([]*menuCompleter, 0, len(cmds)+2)}
+	for _, i := range cmds {
+		if i.IsAvailableCommand() {
+			r.sub = append(r.sub, newMenuCompleter(i, r))
+		}
 	}
-	r.sub = append(r.sub, newSignerMenu(r), helpCommand, exitCommand)
+	r.sub = append(r.sub, helpCommand, exitCommand)
 	return r
 }
 
@@ -59,10 +65,29 @@ func newMenuCompleter(cmd *cobra.Command, parent *menuCompleter) *menuCompleter
 			Description: cmd.Short,
 		},
 		parent: parent,
-		sub:    make([]*menuCompleter, 0, len(cmds)+3),
+		cmd:    cmd,
+	}
+	var validArgs []string
+	if cmd.ValidArgsFunction != nil {
+		validArgs, _ = cmd.ValidArgsFunction(cmd, nil, "")
+	}
+	if len(cmds) == 0 && cmd.ValidArgsFunction == nil {
+		return r
+	}
+	r.sub = make([]*menuCompleter, 0, len(cmds)+len(validArgs)+3)
+	for _, i := range cmds {
+		if i.IsAvailableCommand() {
+			r.sub = append(r.sub, newMenuCompleter(i, r))
+		}
 	}
-	for _, i := range cmd.Commands() {
-		r.sub = append(r.sub, newMenuCompleter(i, r))
+	// valid arguments become the entries of the menu
+	for _, i := range validArgs {
+		parts := strings.SplitN(i, "\t", 2)
+		sug := &prompt.Suggest{Text: parts[0]}
+		if len(parts) > 1 {
+			sug.Description = parts[1]
+		}
+		r.sub = append(r.sub, &menuCompleter{suggestion: sug, parent: r, cmd: cmd, args: []string{parts[0]}})
 	}
 	r.sub = append(r.sub, tailCommands...)
 	return r
@@ -89,8 +114,39 @@ func (cc *menuCompleter) name() string {
 	return strings.Join(parts, nameSep)
 }
 
+// prompt returns the menu path prefixed by the chain and the selected contract
 func (cc *menuCompleter) prompt(p string) string {
-	return cc.name() + p + " "
+	name := cc.name()
+	if sess.sessionContract != nil {
+		if name == "" {
+			name = sess.name
+		} else {
+			name = sess.name + nameSep + name
+		}
+	}
+	if sess.chainID != nil {
+		name = "[" + chainDescription(sess.chainID) + "] " + name
+	}
+	return name + p + " "
+}
+
+// find returns the menu at path, or the closest parent that still exists
+func (cc *menuCompleter) find(path string) *menuCompleter {
+	r := cc
+	if path == "" {
+		return r
+	}
+Outer:
+	for _, p := range strings.Split(path, nameSep) {
+		for _, i := range r.sub {
+			if i.suggestion.Text == p && i.sub != nil {
+				r = i
+				continue Outer
+			}
+		}
+		break
+	}
+	return r
 }
 
 func (cc *menuCompleter) completer(doc prompt.Document) []prompt.Suggest {
@@ -101,23 +157,6 @@ func (cc *menuCompleter) completer(doc prompt.Document) []prompt.Suggest {
 	return prompt.FilterHasPrefix(r, doc.GetWordBeforeCursor(), false)
 }
 
-func newSignerMenu(parent *menuCompleter) *menuCompleter {
-	r := &menuCompleter{parent: parent, suggestion: &prompt.Suggest{
-		Text:        "signer",
-		Description: "configure signer",
-	}}
-	sigKey := &menuCompleter{parent: r, suggestion: &prompt.Suggest{
-		Text:        "key",
-		Description: "sign with a key",
-	}}
-	sigLedger := &menuCompleter{parent: r, suggestion: &prompt.Suggest{
-		Text:        "ledger",
-		Description: "sign with ledger",
-	}}
-	r.sub = append([]*menuCompleter{sigKey, sigLedger}, tailCommands...)
-	return r
-}
-
 func inputMultiChoice(pr string, def string, choices []prompt.Suggest, helpFunc func(c []prompt.Suggest)) (string, bool) {
 	choices = append(choices, *tailCommands[0].suggestion, *tailCommands[1].suggestion)
 	for {
@@ -287,64 +326,76 @@ func inputFilename(pr string, rootPath string, mustExit bool) (string, error) {
 }
 
 func showHelp(node *menuCompleter) {
-	maxSz := 0
+	sugs := make([]prompt.Suggest, 0, len(node.sub))
 	for _, i := range node.sub {
-		if newSz := len(i.suggestion.Text); newSz > maxSz {
+		sugs = append(sugs, *i.suggestion)
+	}
+	showSuggestions(sugs)
+}
+
+func showSuggestions(sugs []prompt.Suggest) {
+	maxSz := 0
+	for _, i := range sugs {
+		if newSz := len(i.Text); newSz > maxSz {
 			maxSz = newSz
 		}
 	}
 	maxSz += 4
-	for _, i := range node.sub {
+	for _, i := range sugs {
 		fmt.Printf(
 			"%s%s%s\n",
-			i.suggestion.Text,
-			strings.Repeat(" ", maxSz-len(i.suggestion.Text)),
-			i.suggestion.Description,
+			i.Text,
+			strings.Repeat(" ", maxSz-len(i.Text)),
+			i.Description,
 		)
 	}
 }
 
-func inputKeyFile() (*ecdsa.PrivateKey, error) {
+// inputKeyFile asks for a key file, returning the key and the file path
+func inputKeyFile() (*ecdsa.PrivateKey, string, error) {
 	p, err := filepath.Abs(".")
 	if err != nil {
-		return nil, err
+		return nil, "", err
 	}
 	keyFile, err := inputFilename("key file: ", p, true)
 	if err != nil {
-		return nil, err
+		return nil, "", err
 	}
 	b, err := ioutil.ReadFile(keyFile)
 	if err != nil {
-		return nil, err
+		return nil, "", err
 	}
 	var k *ecdsa.PrivateKey
 	encrypted, ok := inputYesNo("encrypted? (%s): ", false)
 	if !ok {
-		return nil, errors.New("bad response")
+		return nil, "", errors.New("bad response")
 	}
 	if encrypted {
 		password, err := inputPassword()
 		if err != nil {
-			return nil, err
+			return nil, "", err
 		}
 		ksk, err := keystore.DecryptKey(b, password)
 		if err != nil {
-			return nil, err
+			return nil, "", err
 		}
 		k = ksk.PrivateKey
 	} else if k, err = crypto.HexToECDSA(string(b)); err != nil {
-		return nil, err
+		return nil, "", err
 	}
-	return k, nil
+	return k, keyFile, nil
 }
 
-func inputPassword() (string, error) {
-	fmt.Print("password: ")
-	bytePassword, err := terminal.ReadPassword(int(syscall.Stdin))
+func inputPassword() (string, error) { return inputSecret("password: ") }
+
+// inputSecret reads a line without echoing it
+func inputSecret(pr string) (string, error) {
+	fmt.Print(pr)
+	b, err := terminal.ReadPassword(int(syscall.Stdin))
 	if err != nil {
 		return "", err
 	}
-	return string(bytePassword), nil
+	return string(b), nil
 }
 
 func inputText(pr string) string {
@@ -372,7 +423,6 @@ func inputBigIntWithDefault(pr string, d *big.Int) *big.Int {
 		if v == "" {
 			return d
 		}
-		fmt.Printf("::: %#v\n", v)
 		if r, ok := new(big.Int).SetString(v, 10); ok {
 			return r
 		}
@@ -397,105 +447,32 @@ func inputIntWithDefault(pr string, def int) (int, bool) {
 	}
 }
 
-// func newConfigMenu(parent *menuCompleter) *menuCompleter {
-// 	r := &menuCompleter{
-// 		parent: parent,
-// 		suggestion: &prompt.Suggest{
-// 			Text:        "config",
-// 			Description: "configuration",
-// 		},
-// 	}
-// 	r.sub = append(r.sub, tailCommands...)
-// 	return r
-// 			// 	configClientsCommand := &menuCompleter{
-// 			// 		Parent: r,
-// 			// 		Suggestion: &prompt.Suggest{
-// 			// 			Text:        "clients",
-// 			// 			Description: "configure cryptocurrency clients",
-// 			// 		},
-// 			// 	}
-// 			// 	cc := make([]string, 0, len(cryptos.Cryptos))
-// 			// 	for c := range cryptos.Cryptos {
-// 			// 		cc = append(cc, c)
-// 			// 	}
-// 			// 	sort.Strings(cc)
-// 			// 	for _, c := range cc {
-// 			// 		configClientsCommand.Sub = append(configClientsCommand.Sub, newClientConfigMenu(c, configClientsCommand))
-// 			// 	}
-// 			// 	configClientsCommand.Sub = append(configClientsCommand.Sub, tailCommands...)
-// 			// 	r.Sub = append(r.Sub,
-// 			// 		configClientsCommand,
-// 			// 		&menuCompleter{
-// 			// 			Parent: r,
-// 			// 			Suggestion: &prompt.Suggest{
-// 			// 				Text:        "save",
-// 			// 				Description: "save configuration",
-// 			// 			},
-// 			// 		},
-// 			// 		&menuCompleter{
-// 			// 			Parent: r,
-// 			// 			Suggestion: &prompt.Suggest{
-// 			// 				Text:        "load",
-// 			// 				Description: "load configuration",
-// 			// 			},
-// 			// 		},
-// 			// 		&menuCompleter{
-// 			// 			Parent: r,
-// 			// 			Suggestion: &prompt.Suggest{
-// 			// 				Text:        "show",
-// 			// 				Description: "show the current configuration",
-// 			// 			},
-// 			// 		},
-// 			// 	)
-// }
-
-// func newClientConfigMenu(name string, parent *menuCompleter) *menuCompleter {
-// 	r := &menuCompleter{
-// 		Parent: parent,
-// 		Suggestion: &prompt.Suggest{
-// 			Text:        name,
-// 			Description: fmt.Sprintf("configure %s client", name),
-// 		},
-// 	}
-// 	r.Sub = append(r.Sub,
-// 		&menuCompleter{
-// 			Parent: r,
-// 			Suggestion: &prompt.Suggest{
-// 				Text:        "address",
-// 				Description: "configure the address",
-// 			},
-// 		},
-// 		&menuCompleter{
-// 			Parent: r,
-// 			Suggestion: &prompt.Suggest{
-// 				Text:        "username",
-// 				Description: "configure the username",
-// 			},
-// 		},
-// 		&menuCompleter{
-// 			Parent: r,
-// 			Suggestion: &prompt.Suggest{
-// 				Text:        "password",
-// 				Description: "configure the password",
-// 			},
-// 		},
-// 		newClientTLSConfigMenu(r),
-// 		&menuCompleter{
-// 			Parent: r,
-// 			Suggestion: &prompt.Suggest{
-// 				Text:        "show",
-// 				Description: "show configuration",
-// 			},
-// 		},
-// 	)
-// 	r.Sub = append(r.Sub, tailCommands...)
-// 	return r
-// }
-
-// func inputTextWithDefault(pr, def string) string {
-// 	r := inputText(fmt.Sprintf("%s (%s)", pr, def))
-// 	if strings.TrimSpace(r) == "" {
-// 		return def
-// 	}
-// 	return r
-// }
+func inputDurationWithDefault(pr string, def time.Duration) (time.Duration, bool) {
+	for {
+		v := inputText(fmt.Sprintf(pr, def))
+		if v == "" {
+			return def, true
+		} else if v == ".." {
+			fmt.Println("aborted")
+			return 0, false
+		}
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			fmt.Printf("%#v is not a duration: %s\n", v, err)
+			continue
+		}
+		return d, true
+	}
+}
+
+func inputTextWithDefault(pr, def string) (string, bool) {
+	v := strings.TrimSpace(inputText(fmt.Sprintf(pr, def)))
+	switch v {
+	case "":
+		return def, true
+	case "..":
+		fmt.Println("aborted")
+		return "", false
+	}
+	return v, true
+}
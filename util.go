@@ -1,28 +1,90 @@
 package main
 
 import (
+	"context"
 	"fmt"
+	"io/ioutil"
+	"math/big"
 	"os"
+	"os/signal"
+	"reflect"
+	"strings"
+	"syscall"
 
-	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/params"
 )
 
-func readABI(fn string) (*abi.ABI, error) {
-	f, err := os.Open(fn)
+// interruptContext returns a context that is canceled when the user presses ctrl-c
+func interruptContext() (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(context.Background())
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, syscall.SIGTERM, syscall.SIGINT)
+	go func() {
+		select {
+		case <-sig:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	return ctx, func() {
+		signal.Stop(sig)
+		cancel()
+	}
+}
+
+// readBytecode reads a hex encoded bytecode file or the bytecode of the named contract in an artifact
+func readBytecode(fn, name string) ([]byte, error) {
+	b, err := ioutil.ReadFile(fn)
 	if err != nil {
 		return nil, err
 	}
-	defer f.Close()
-	r, err := abi.JSON(f)
+	s := strings.TrimSpace(string(b))
+	if !strings.HasPrefix(s, "{") {
+		return parseHex(s)
+	}
+	a, err := loadArtifact(fn, name, interactive)
 	if err != nil {
 		return nil, err
 	}
-	return &r, nil
+	return a.deployBytecode()
+}
+
+// formatEther formats an amount of wei in ether
+func formatEther(wei *big.Int) string { return formatUnits(wei, params.Ether, 18) }
+
+// formatGwei formats an amount of wei in gwei
+func formatGwei(wei *big.Int) string { return formatUnits(wei, params.GWei, 9) }
+
+func formatUnits(wei *big.Int, unit int64, decimals int) string {
+	q, r := new(big.Int).QuoRem(new(big.Int).Abs(wei), big.NewInt(unit), new(big.Int))
+	var sign string
+	if wei.Sign() < 0 {
+		sign = "-"
+	}
+	frac := strings.TrimRight(fmt.Sprintf("%0*s", decimals, r), "0")
+	if frac == "" {
+		return sign + q.String()
+	}
+	return sign + q.String() + "." + frac
 }
 
-func errorExit(code int, f string, a ...interface{}) {
-	fmt.Fprintf(os.Stderr, f, a...)
-	os.Exit(code)
+func formatValue(v interface{}) interface{} {
+	switch vv := v.(type) {
+	case common.Address:
+		return vv.Hex()
+	case common.Hash:
+		return vv.Hex()
+	case []byte:
+		return hexutil.Encode(vv)
+	}
+	if rv := reflect.ValueOf(v); rv.Kind() == reflect.Array && rv.Type().Elem().Kind() == reflect.Uint8 {
+		b := make([]byte, rv.Len())
+		reflect.Copy(reflect.ValueOf(b), rv)
+		return hexutil.Encode(b)
+	}
+	return v
 }
 
 // func findNotDefinedCommands(node *menuCompleter, out chan string, isRoot bool) {
@@ -0,0 +1,257 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"reflect"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+var (
+	errInvalidBool   = errors.New("expecting true or false")
+	errInvalidNumber = errors.New("not a number")
+	errOutOfRange    = errors.New("value out of range")
+	errNotAddress    = errors.New("not an address")
+	errInvalidHex    = errors.New("invalid hex value")
+)
+
+// parseValue parses the text representation of a value of type t
+//
+// arrays are accepted as json or as comma separated lists, tuples as json objects or arrays and bytes as hex
+func parseValue(t abi.Type, s string) (interface{}, error) {
+	switch t.T {
+	case abi.SliceTy, abi.ArrayTy:
+		if ts := strings.TrimSpace(s); !strings.HasPrefix(ts, "[") {
+			return convertValue(t, splitList(ts))
+		}
+		fallthrough
+	case abi.TupleTy:
+		v, err := decodeJSON(s)
+		if err != nil {
+			return nil, err
+		}
+		return convertValue(t, v)
+	}
+	return parseScalar(t, s)
+}
+
+func decodeJSON(s string) (interface{}, error) {
+	dec := json.NewDecoder(strings.NewReader(s))
+	dec.UseNumber()
+	var r interface{}
+	if err := dec.Decode(&r); err != nil {
+		return nil, fmt.Errorf("invalid json: %w", err)
+	}
+	return r, nil
+}
+
+func splitList(s string) []interface{} {
+	if s == "" {
+		return []interface{}{}
+	}
+	parts := strings.Split(s, ",")
+	r := make([]interface{}, 0, len(parts))
+	for _, i := range parts {
+		r = append(r, strings.TrimSpace(i))
+	}
+	return r
+}
+
+// convertValue converts a decoded json value to a value of type t
+func convertValue(t abi.Type, v interface{}) (interface{}, error) {
+	switch t.T {
+	case abi.SliceTy, abi.ArrayTy:
+		items, ok := v.([]interface{})
+		if !ok {
+			return nil, fmt.Errorf("expecting a list for %s", t.String())
+		}
+		var r reflect.Value
+		if t.T == abi.ArrayTy {
+			if len(items) != t.Size {
+				return nil, fmt.Errorf("expecting %d items, got %d", t.Size, len(items))
+			}
+			r = reflect.New(t.GetType()).Elem()
+		} else {
+			r = reflect.MakeSlice(t.GetType(), len(items), len(items))
+		}
+		for n, i := range items {
+			iv, err := convertValue(*t.Elem, i)
+			if err != nil {
+				return nil, fmt.Errorf("[%d]: %w", n, err)
+			}
+			r.Index(n).Set(reflect.ValueOf(iv))
+		}
+		return r.Interface(), nil
+	case abi.TupleTy:
+		r := reflect.New(t.GetType()).Elem()
+		switch vv := v.(type) {
+		case map[string]interface{}:
+			for n, i := range t.TupleElems {
+				name := tupleFieldName(t, n)
+				fv, ok := vv[name]
+				if !ok {
+					return nil, fmt.Errorf("missing field %s", name)
+				}
+				iv, err := convertValue(*i, fv)
+				if err != nil {
+					return nil, fmt.Errorf("%s: %w", name, err)
+				}
+				r.Field(n).Set(reflect.ValueOf(iv))
+			}
+		case []interface{}:
+			if len(vv) != len(t.TupleElems) {
+				return nil, fmt.Errorf("expecting %d fields, got %d", len(t.TupleElems), len(vv))
+			}
+			for n, i := range t.TupleElems {
+				iv, err := convertValue(*i, vv[n])
+				if err != nil {
+					return nil, fmt.Errorf("%s: %w", tupleFieldName(t, n), err)
+				}
+				r.Field(n).Set(reflect.ValueOf(iv))
+			}
+		default:
+			return nil, fmt.Errorf("expecting an object for %s", t.String())
+		}
+		return r.Interface(), nil
+	}
+	switch vv := v.(type) {
+	case string:
+		return parseScalar(t, vv)
+	case json.Number:
+		return parseScalar(t, vv.String())
+	case bool:
+		if t.T != abi.BoolTy {
+			return nil, fmt.Errorf("unexpected bool for %s", t.String())
+		}
+		return vv, nil
+	}
+	return nil, fmt.Errorf("unexpected value for %s", t.String())
+}
+
+func tupleFieldName(t abi.Type, n int) string {
+	if name := t.TupleRawNames[n]; name != "" {
+		return name
+	}
+	return fmt.Sprintf("arg%d", n)
+}
+
+func parseScalar(t abi.Type, s string) (interface{}, error) {
+	s = strings.TrimSpace(s)
+	switch t.T {
+	case abi.StringTy:
+		// accept quoted strings so the empty string can be entered
+		if len(s) >= 2 && s[0] == '"' {
+			var r string
+			if err := json.Unmarshal([]byte(s), &r); err == nil {
+				return r, nil
+			}
+		}
+		return s, nil
+	case abi.BoolTy:
+		switch strings.ToLower(s) {
+		case "true", "yes", "1":
+			return true, nil
+		case "false", "no", "0":
+			return false, nil
+		}
+		return nil, errInvalidBool
+	case abi.IntTy, abi.UintTy:
+		return parseInteger(t, s)
+	case abi.AddressTy:
+		if !common.IsHexAddress(s) {
+			return nil, errNotAddress
+		}
+		return common.HexToAddress(s), nil
+	case abi.BytesTy:
+		return parseHex(s)
+	case abi.FixedBytesTy, abi.FunctionTy:
+		b, err := parseHex(s)
+		if err != nil {
+			return nil, err
+		}
+		r := reflect.New(t.GetType()).Elem()
+		if len(b) != r.Len() {
+			return nil, fmt.Errorf("expecting %d bytes, got %d", r.Len(), len(b))
+		}
+		reflect.Copy(r, reflect.ValueOf(b))
+		return r.Interface(), nil
+	}
+	return nil, fmt.Errorf("unsupported type: %s", t.String())
+}
+
+func parseHex(s string) ([]byte, error) {
+	if !strings.HasPrefix(s, "0x") && !strings.HasPrefix(s, "0X") {
+		s = "0x" + s
+	}
+	if s == "0x" {
+		return []byte{}, nil
+	}
+	b, err := hexutil.Decode(s)
+	if err != nil {
+		return nil, errInvalidHex
+	}
+	return b, nil
+}
+
+// parseBigInt parses a decimal integer, or a hex one with the 0x prefix
+func parseBigInt(s string) (*big.Int, bool) {
+	digits := strings.TrimPrefix(s, "-")
+	base := 10
+	if strings.HasPrefix(digits, "0x") || strings.HasPrefix(digits, "0X") {
+		base, digits = 16, digits[2:]
+	}
+	if digits == "" || digits[0] == '+' || digits[0] == '-' {
+		return nil, false
+	}
+	v, ok := new(big.Int).SetString(digits, base)
+	if !ok {
+		return nil, false
+	}
+	if strings.HasPrefix(s, "-") {
+		v.Neg(v)
+	}
+	return v, true
+}
+
+func parseInteger(t abi.Type, s string) (interface{}, error) {
+	v, ok := parseBigInt(s)
+	if !ok {
+		return nil, errInvalidNumber
+	}
+	limit := new(big.Int).Lsh(big.NewInt(1), uint(t.Size))
+	if t.T == abi.IntTy {
+		limit.Rsh(limit, 1)
+		if v.Cmp(limit) >= 0 || v.Cmp(new(big.Int).Neg(limit)) < 0 {
+			return nil, errOutOfRange
+		}
+	} else if v.Sign() < 0 || v.Cmp(limit) >= 0 {
+		return nil, errOutOfRange
+	}
+	r := reflect.New(t.GetType()).Elem()
+	switch r.Kind() {
+	case reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		r.SetUint(v.Uint64())
+	case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		r.SetInt(v.Int64())
+	default:
+		return v, nil
+	}
+	return r.Interface(), nil
+}
+
+// typeHint describes the accepted input for types that aren't obvious
+func typeHint(t abi.Type) string {
+	switch t.T {
+	case abi.SliceTy, abi.ArrayTy:
+		return "comma separated or json"
+	case abi.BytesTy, abi.FixedBytesTy:
+		return "hex"
+	}
+	return ""
+}
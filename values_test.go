@@ -0,0 +1,142 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+)
+
+func testType(t *testing.T, typ string, components ...abi.ArgumentMarshaling) abi.Type {
+	r, err := abi.NewType(typ, "", components)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return r
+}
+
+// formatTestValue returns the json of a parsed value, comparable across types
+func formatTestValue(t *testing.T, typ abi.Type, v interface{}) string {
+	b, err := json.Marshal(jsonValue(typ, v))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(b)
+}
+
+func TestParseValue(t *testing.T) {
+	pair := []abi.ArgumentMarshaling{{Name: "a", Type: "uint256"}, {Name: "b", Type: "address"}}
+	const addr = "0x1111111111111111111111111111111111111111"
+	for _, i := range []struct {
+		typ      abi.Type
+		s        string
+		expected string // json of the value, empty when parsing fails
+	}{
+		{testType(t, "uint256"), "10", `"10"`},
+		{testType(t, "uint256"), "010", `"10"`},
+		{testType(t, "uint256"), "0100", `"100"`},
+		{testType(t, "uint256"), "08", `"8"`},
+		{testType(t, "uint256"), " 42 ", `"42"`},
+		{testType(t, "uint256"), "0x10", `"16"`},
+		{testType(t, "uint256"), "0X1f", `"31"`},
+		{testType(t, "uint256"), "115792089237316195423570985008687907853269984665640564039457584007913129639935", `"115792089237316195423570985008687907853269984665640564039457584007913129639935"`},
+		{testType(t, "uint256"), "115792089237316195423570985008687907853269984665640564039457584007913129639936", ""},
+		{testType(t, "uint256"), "-1", ""},
+		{testType(t, "uint256"), "0b11", ""},
+		{testType(t, "uint256"), "0o17", ""},
+		{testType(t, "uint256"), "1_000", ""},
+		{testType(t, "uint256"), "1e3", ""},
+		{testType(t, "uint256"), "0x", ""},
+		{testType(t, "uint256"), "", ""},
+		{testType(t, "uint8"), "255", `255`},
+		{testType(t, "uint8"), "256", ""},
+		{testType(t, "uint64"), "18446744073709551615", `"18446744073709551615"`},
+		{testType(t, "int8"), "-128", `-128`},
+		{testType(t, "int8"), "-0x80", `-128`},
+		{testType(t, "int8"), "-010", `-10`},
+		{testType(t, "int8"), "128", ""},
+		{testType(t, "int8"), "--1", ""},
+		{testType(t, "int8"), "+1", ""},
+		{testType(t, "int256"), "-57896044618658097711785492504343953926634992332820282019728792003956564819968", `"-57896044618658097711785492504343953926634992332820282019728792003956564819968"`},
+		{testType(t, "bool"), "true", `true`},
+		{testType(t, "bool"), "No", `false`},
+		{testType(t, "bool"), "2", ""},
+		{testType(t, "string"), "hello world", `"hello world"`},
+		{testType(t, "string"), `""`, `""`},
+		{testType(t, "address"), addr, `"` + addr + `"`},
+		{testType(t, "address"), "0x1111", ""},
+		{testType(t, "bytes"), "0xabcd", `"0xabcd"`},
+		{testType(t, "bytes"), "abcd", `"0xabcd"`},
+		{testType(t, "bytes"), "", `"0x"`},
+		{testType(t, "bytes"), "0xabc", ""},
+		{testType(t, "bytes4"), "0x01020304", `"0x01020304"`},
+		{testType(t, "bytes4"), "0x0102", ""},
+		{testType(t, "uint8[]"), "1, 2,3", `[1,2,3]`},
+		{testType(t, "uint8[]"), `[1, "2", "0x03"]`, `[1,2,3]`},
+		{testType(t, "uint8[]"), "", `[]`},
+		{testType(t, "uint8[]"), "1,x", ""},
+		{testType(t, "uint8[2]"), "1,2", `[1,2]`},
+		{testType(t, "uint8[2]"), "1,2,3", ""},
+		{testType(t, "uint256[2][]"), "[[1,2],[3,4]]", `[["1","2"],["3","4"]]`},
+		{testType(t, "address[]"), addr + "," + addr, `["` + addr + `","` + addr + `"]`},
+		{testType(t, "bool[]"), "[true,false]", `[true,false]`},
+		{testType(t, "tuple", pair...), `{"a": 5, "b": "` + addr + `"}`, `{"a":"5","b":"` + addr + `"}`},
+		{testType(t, "tuple", pair...), `[5, "` + addr + `"]`, `{"a":"5","b":"` + addr + `"}`},
+		{testType(t, "tuple", pair...), `{"a": 5}`, ""},
+		{testType(t, "tuple", pair...), `[5]`, ""},
+		{testType(t, "tuple", pair...), `5`, ""},
+		{testType(t, "tuple[]", pair...), `[{"a": 1, "b": "` + addr + `"}, [2, "` + addr + `"]]`, `[{"a":"1","b":"` + addr + `"},{"a":"2","b":"` + addr + `"}]`},
+	} {
+		v, err := parseValue(i.typ, i.s)
+		if i.expected == "" {
+			if err == nil {
+				t.Errorf("%s %q: expecting an error, got %s", i.typ, i.s, formatTestValue(t, i.typ, v))
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s %q: %s", i.typ, i.s, err)
+			continue
+		}
+		if r := formatTestValue(t, i.typ, v); r != i.expected {
+			t.Errorf("%s %q: expecting %s, got %s", i.typ, i.s, i.expected, r)
+		}
+	}
+}
+
+func TestParseArguments(t *testing.T) {
+	args := abi.Arguments{
+		{Name: "to", Type: testType(t, "address")},
+		{Name: "amounts", Type: testType(t, "uint256[]")},
+		{Type: testType(t, "bool")},
+	}
+	const addr = "0x1111111111111111111111111111111111111111"
+	for _, i := range []struct {
+		values   []string
+		expected string // json of the values, or the error
+	}{
+		{[]string{"to=" + addr, "amounts=1,2", "arg2=yes"}, `["` + addr + `",["1","2"],true]`},
+		{[]string{"arg2=no", "amounts=[]", "to=" + addr}, `["` + addr + `",[],false]`},
+		{[]string{"amounts=1", "arg2=yes"}, "missing argument: to"},
+		{[]string{"to=" + addr, "amounts=1", "arg2=yes", "other=1"}, "unknown argument: other"},
+		{[]string{"to=" + addr, "to=" + addr, "amounts=1", "arg2=yes"}, "duplicated value: to"},
+		{[]string{"to", "amounts=1", "arg2=yes"}, "expecting name=value: to"},
+		{[]string{"to=0x1", "amounts=1", "arg2=yes"}, "can't parse argument to: " + errNotAddress.Error()},
+	} {
+		values, err := parseArguments(args, i.values)
+		if err != nil {
+			if err.Error() != i.expected {
+				t.Errorf("%s: expecting %s, got error %s", strings.Join(i.values, " "), i.expected, err)
+			}
+			continue
+		}
+		r := make([]string, 0, len(values))
+		for n, v := range values {
+			r = append(r, formatTestValue(t, args[n].Type, v))
+		}
+		if s := "[" + strings.Join(r, ",") + "]"; s != i.expected {
+			t.Errorf("%s: expecting %s, got %s", strings.Join(i.values, " "), i.expected, s)
+		}
+	}
+}
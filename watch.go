@@ -0,0 +1,282 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/event"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+type watchSettings struct {
+	// poll polls for new logs even when the endpoint supports subscriptions
+	poll         bool
+	pollInterval time.Duration
+	// confirmations is the depth of the blocks of the confirmed events (1 for the head block)
+	confirmations int
+}
+
+var watchConfig = watchSettings{pollInterval: 4 * time.Second, confirmations: 1}
+
+var (
+	errInvalidPollInterval       = errors.New("poll interval must be positive")
+	errInvalidWatchConfirmations = errors.New("confirmations must be at least 1")
+)
+
+// subscriptionsUnsupported reports whether the subscription failed because the transport or the
+// endpoint doesn't support them (http endpoints, providers without eth_subscribe)
+func subscriptionsUnsupported(err error) bool {
+	if errors.Is(err, rpc.ErrNotificationsUnsupported) {
+		return true
+	}
+	var re rpc.Error
+	if errors.As(err, &re) && re.ErrorCode() == -32601 {
+		return true
+	}
+	return strings.Contains(err.Error(), "notifications not supported")
+}
+
+// confirmation status of the watched events
+const (
+	eventPending   = "pending"
+	eventConfirmed = "confirmed"
+	eventRemoved   = "removed"
+)
+
+// logConfirmations holds the watched logs until they are confirmations blocks deep, retracting
+// the logs removed by reorganisations
+type logConfirmations struct {
+	cl            backend
+	confirmations uint64
+	pending       []types.Log
+	// confirmedTo is the last block whose logs were confirmed
+	confirmedTo uint64
+	fn          func(l types.Log, status string) error
+}
+
+func newLogConfirmations(cl backend, confirmations int, fn func(l types.Log, status string) error) *logConfirmations {
+	return &logConfirmations{cl: cl, confirmations: uint64(confirmations), fn: fn}
+}
+
+func (c *logConfirmations) find(l types.Log) int {
+	return findLog(c.pending, l)
+}
+
+func findLog(logs []types.Log, l types.Log) int {
+	for n, i := range logs {
+		if i.BlockHash == l.BlockHash && i.Index == l.Index {
+			return n
+		}
+	}
+	return -1
+}
+
+func (c *logConfirmations) add(l types.Log) error {
+	n := c.find(l)
+	if l.Removed {
+		if n >= 0 {
+			c.pending = append(c.pending[:n], c.pending[n+1:]...)
+		}
+		return c.fn(l, eventRemoved)
+	}
+	if n >= 0 {
+		return nil
+	}
+	if c.confirmations <= 1 {
+		return c.fn(l, eventConfirmed)
+	}
+	c.pending = append(c.pending, l)
+	return c.fn(l, eventPending)
+}
+
+// update confirms the pending logs that are deep enough at the head block. logs whose block
+// isn't in the chain anymore are removed
+func (c *logConfirmations) update(ctx context.Context, head uint64) error {
+	if c.confirmations <= 1 {
+		return nil
+	}
+	hashes := make(map[uint64]common.Hash, 4)
+	pending := c.pending[:0]
+	for n, l := range c.pending {
+		if head+1 < l.BlockNumber+c.confirmations {
+			pending = append(pending, l)
+			continue
+		}
+		hash, ok := hashes[l.BlockNumber]
+		if !ok {
+			h, err := c.cl.HeaderByNumber(ctx, new(big.Int).SetUint64(l.BlockNumber))
+			if err != nil {
+				c.pending = append(pending, c.pending[n:]...)
+				return fmt.Errorf("can't get block %d: %w", l.BlockNumber, err)
+			}
+			hash = h.Hash()
+			hashes[l.BlockNumber] = hash
+		}
+		status := eventConfirmed
+		if hash != l.BlockHash {
+			l.Removed = true
+			status = eventRemoved
+		}
+		if err := c.fn(l, status); err != nil {
+			c.pending = append(pending, c.pending[n+1:]...)
+			return err
+		}
+	}
+	c.pending = pending
+	if head+1 >= c.confirmations && head+1-c.confirmations > c.confirmedTo {
+		c.confirmedTo = head + 1 - c.confirmations
+	}
+	return nil
+}
+
+// retract removes the pending logs that a new query of their blocks didn't return, their blocks
+// were replaced by a reorganisation
+func (c *logConfirmations) retract(logs []types.Log) error {
+	pending := c.pending[:0]
+	for n, l := range c.pending {
+		if findLog(logs, l) >= 0 {
+			pending = append(pending, l)
+			continue
+		}
+		l.Removed = true
+		if err := c.fn(l, eventRemoved); err != nil {
+			c.pending = append(pending, c.pending[n+1:]...)
+			return err
+		}
+	}
+	c.pending = pending
+	return nil
+}
+
+// watchEvents prints the new logs of the query as they are mined. what describes the watched
+// events and names adds the event names to the text output
+func watchEvents(cl backend, contractABI *abi.ABI, query ethereum.FilterQuery, what string, names bool) error {
+	if watchConfig.confirmations < 1 {
+		return errInvalidWatchConfirmations
+	}
+	if watchConfig.pollInterval <= 0 {
+		return errInvalidPollInterval
+	}
+	c := newLogConfirmations(cl, watchConfig.confirmations, logPrinter(contractABI, &eventPrinter{names: names}))
+	var depth string
+	if c.confirmations > 1 {
+		depth = fmt.Sprintf(" (%d confirmations)", c.confirmations)
+	}
+	ctx, cancel := interruptContext()
+	defer cancel()
+	if !watchConfig.poll {
+		logs := make(chan types.Log)
+		sub, err := cl.SubscribeFilterLogs(ctx, query, logs)
+		if err == nil {
+			infof("watching %s%s with a subscription, press ctrl-c to stop\n", what, depth)
+			return watchSubscription(ctx, logs, sub, c, watchConfig.pollInterval)
+		}
+		if !subscriptionsUnsupported(err) {
+			return fmt.Errorf("error watching logs: %w", err)
+		}
+		infof("subscriptions not supported by the endpoint\n")
+	}
+	infof("watching %s%s polling every %s, press ctrl-c to stop\n", what, depth, watchConfig.pollInterval)
+	if err := pollLogs(ctx, cl, query, watchConfig.pollInterval, c); err != nil {
+		return fmt.Errorf("error watching logs: %w", err)
+	}
+	return nil
+}
+
+// watchSubscription passes the logs of the subscription to the confirmations, checking the head
+// block every interval to confirm them
+func watchSubscription(ctx context.Context, logs chan types.Log, sub event.Subscription, c *logConfirmations, interval time.Duration) error {
+	defer close(logs)
+	defer sub.Unsubscribe()
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case err := <-sub.Err():
+			if err != nil {
+				return fmt.Errorf("error watching logs: %w", err)
+			}
+		case l := <-logs:
+			if err := c.add(l); err != nil {
+				return fmt.Errorf("error watching logs: %w", err)
+			}
+		case <-t.C:
+			if len(c.pending) == 0 {
+				continue
+			}
+			h, err := c.cl.HeaderByNumber(ctx, nil)
+			if err == nil {
+				err = c.update(ctx, h.Number.Uint64())
+			}
+			if ctx.Err() != nil {
+				return nil
+			} else if err != nil {
+				return fmt.Errorf("error watching logs: %w", err)
+			}
+		}
+	}
+}
+
+// pollLogs runs the query over the blocks mined since the last poll, starting after the current
+// block, until the context is canceled. the blocks that aren't confirmed yet are queried again to
+// find the logs of reorganisations, the pending logs not found again are retracted
+func pollLogs(ctx context.Context, cl backend, query ethereum.FilterQuery, interval time.Duration, c *logConfirmations) error {
+	h, err := cl.HeaderByNumber(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("can't get the last block: %w", err)
+	}
+	next := h.Number.Uint64() + 1
+	c.confirmedTo = h.Number.Uint64()
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-t.C:
+		}
+		if h, err = cl.HeaderByNumber(ctx, nil); err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return fmt.Errorf("can't get the last block: %w", err)
+		}
+		last := h.Number.Uint64()
+		if c.confirmations > 1 && c.confirmedTo+1 < next {
+			next = c.confirmedTo + 1
+		}
+		// the pending logs are all in the blocks queried again
+		var found []types.Log
+		if last >= next {
+			next, err = scanLogs(ctx, cl, query, next, last, func(l types.Log) error {
+				found = append(found, l)
+				return c.add(l)
+			})
+			if err != nil {
+				if ctx.Err() != nil {
+					return nil
+				}
+				return err
+			}
+		}
+		if err = c.retract(found); err == nil {
+			err = c.update(ctx, last)
+		}
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+	}
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+func TestLogConfirmationsRetract(t *testing.T) {
+	var events []string
+	c := newLogConfirmations(nil, 3, func(l types.Log, status string) error {
+		events = append(events, fmt.Sprintf("%d/%d %s %v", l.BlockNumber, l.Index, status, l.Removed))
+		return nil
+	})
+	a := types.Log{BlockNumber: 5, BlockHash: common.HexToHash("0x05"), Index: 0}
+	b := types.Log{BlockNumber: 6, BlockHash: common.HexToHash("0x06"), Index: 1}
+	// b moved to another block by a reorganisation
+	moved := types.Log{BlockNumber: 7, BlockHash: common.HexToHash("0x07"), Index: 0}
+	for _, i := range []types.Log{a, b, a} {
+		if err := c.add(i); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// the next poll finds a again, b in another block
+	for _, i := range []types.Log{a, moved} {
+		if err := c.add(i); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := c.retract([]types.Log{a, moved}); err != nil {
+		t.Fatal(err)
+	}
+	// the last poll finds nothing, the blocks were replaced
+	if err := c.retract(nil); err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{
+		"5/0 pending false",
+		"6/1 pending false",
+		"7/0 pending false",
+		"6/1 removed true",
+		"5/0 removed true",
+		"7/0 removed true",
+	}
+	if !reflect.DeepEqual(events, expected) {
+		t.Errorf("expecting %q, got %q", expected, events)
+	}
+	if len(c.pending) != 0 {
+		t.Errorf("expecting no pending logs, got %d", len(c.pending))
+	}
+}